@@ -0,0 +1,144 @@
+package e131
+
+import (
+	"fmt"
+	"io"
+)
+
+// field is a named span of an sACN packet. A size of -1 extends the field to
+// the end of the packet.
+type field struct {
+	name string
+	size int
+}
+
+var rootLayerFields = []field{
+	{"Preamble Size", 2},
+	{"Post-amble Size", 2},
+	{"ACN Packet Identifier", 12},
+	{"Root Flags and Length", 2},
+	{"Root Vector", 4},
+	{"CID", 16},
+}
+
+var dataPacketFields = []field{
+	{"Framing Flags and Length", 2},
+	{"Framing Vector", 4},
+	{"Source Name", 64},
+	{"Priority", 1},
+	{"Synchronization Address", 2},
+	{"Sequence Number", 1},
+	{"Options", 1},
+	{"Universe", 2},
+	{"DMP Flags and Length", 2},
+	{"DMP Vector", 1},
+	{"Address Type & Data Type", 1},
+	{"First Property Address", 2},
+	{"Address Increment", 2},
+	{"Property Value Count", 2},
+	{"START Code", 1},
+	{"Property Values", -1},
+}
+
+var syncPacketFields = []field{
+	{"Framing Flags and Length", 2},
+	{"Framing Vector", 4},
+	{"Sequence Number", 1},
+	{"Synchronization Address", 2},
+	{"Reserved", 2},
+}
+
+var discPacketFields = []field{
+	{"Framing Flags and Length", 2},
+	{"Framing Vector", 4},
+	{"Source Name", 64},
+	{"Reserved", 4},
+	{"Discovery Flags and Length", 2},
+	{"Discovery Vector", 4},
+	{"Page", 1},
+	{"Last Page", 1},
+	{"List of Universes", -1},
+}
+
+// Annotate writes a hexdump of packet to w with each field labelled by its
+// name in the E1.31 specification. The layout is chosen from the root and
+// framing vectors; bytes that cannot be attributed to a known layout are
+// reported as unknown.
+func Annotate(w io.Writer, packet []byte) error {
+	fields := append([]field(nil), rootLayerFields...)
+	switch Classify(packet) {
+	case PacketData:
+		fields = append(fields, dataPacketFields...)
+	case PacketSync:
+		fields = append(fields, syncPacketFields...)
+	case PacketDiscovery:
+		fields = append(fields, discPacketFields...)
+	}
+	fields = append(fields, field{"Unknown", -1})
+
+	offset := 0
+	for _, f := range fields {
+		if offset >= len(packet) {
+			break
+		}
+		end := offset + f.size
+		if f.size < 0 || end > len(packet) {
+			end = len(packet)
+		}
+		if err := annotateField(w, offset, packet[offset:end], f.name); err != nil {
+			return err
+		}
+		if end-offset < f.size {
+			_, err := fmt.Fprintf(w, "%04x  (truncated: %s needs %d bytes)\n", end, f.name, f.size)
+			return err
+		}
+		offset = end
+	}
+	return nil
+}
+
+// AnnotateLabels writes the same dump as Annotate and, for data packets,
+// follows it with the value of every channel in the packet that has an entry
+// in labels.
+func AnnotateLabels(w io.Writer, packet []byte, labels Labels) error {
+	if err := Annotate(w, packet); err != nil {
+		return err
+	}
+	f, err := ParseDataPacket(packet)
+	if err != nil || f.StartCode != NullStartCode {
+		return nil
+	}
+	for i, v := range f.Universe.Slots[:f.SlotCount] {
+		l, ok := labels[ChannelAddr{f.Universe.Number, i}]
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%04x  %02x  address %d = %d  %s\n", dataPacketMinSize+i, v, i+1, v, l); err != nil {
+			return err
+		}
+		if l.Notes != "" {
+			if _, err := fmt.Fprintf(w, "      %s\n", l.Notes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// annotateField writes b as rows of up to 16 hex bytes, labelling the first.
+func annotateField(w io.Writer, offset int, b []byte, name string) error {
+	for i := 0; i < len(b); i += 16 {
+		row := b[i:]
+		if len(row) > 16 {
+			row = row[:16]
+		}
+		label := ""
+		if i == 0 {
+			label = name
+		}
+		if _, err := fmt.Fprintf(w, "%04x  % -47x  %s\n", offset+i, row, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package e131
+
+// Per-packet overhead, in bytes, used by the bandwidth estimator.
+const (
+	// dataPacketHeaderSize is the size of an E1.31 data packet up to and
+	// including the DMX start code.
+	dataPacketHeaderSize = 126
+	// udpIPv4HeaderSize is the size of the UDP and IPv4 headers.
+	udpIPv4HeaderSize = 8 + 20
+	// ethernetFrameOverhead is the Ethernet header, FCS, preamble and
+	// inter-frame gap that every packet costs on the wire.
+	ethernetFrameOverhead = 14 + 4 + 8 + 12
+)
+
+// Bandwidth is the expected network load of a set of universes.
+type Bandwidth struct {
+	// PacketsPerSecond is the number of data packets sent each second.
+	PacketsPerSecond float64
+	// PayloadBytesPerSecond counts only the E1.31 packet bytes (the UDP
+	// payload).
+	PayloadBytesPerSecond float64
+	// WireBitsPerSecond includes UDP, IPv4 and Ethernet framing and is the
+	// figure to compare against link capacity.
+	WireBitsPerSecond float64
+}
+
+// EstimateBandwidth returns the expected load of sending the given number of
+// universes, each carrying slots DMX slots (1-512), at fps frames per second.
+func EstimateBandwidth(universes int, fps float64, slots int) (Bandwidth, error) {
+	if universes < 0 {
+		return Bandwidth{}, errorf(ErrInvalidArgument, "Cannot estimate bandwidth for a negative universe count")
+	}
+	if fps < 0 {
+		return Bandwidth{}, errorf(ErrInvalidArgument, "Cannot estimate bandwidth for a negative frame rate")
+	}
+	if slots < 1 || slots > 512 {
+		return Bandwidth{}, errorf(ErrInvalidArgument, "Unable to estimate bandwidth (slot count out of bounds)")
+	}
+
+	payload := float64(dataPacketHeaderSize + slots)
+	wire := payload + udpIPv4HeaderSize + ethernetFrameOverhead
+	pps := float64(universes) * fps
+
+	return Bandwidth{
+		PacketsPerSecond:      pps,
+		PayloadBytesPerSecond: pps * payload,
+		WireBitsPerSecond:     pps * wire * 8,
+	}, nil
+}
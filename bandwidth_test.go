@@ -0,0 +1,52 @@
+package e131
+
+import (
+	"math"
+	"testing"
+)
+
+// TestEstimateBandwidthMeasured checks the estimate against the bytes a
+// Sender actually writes: frames frames of each universe are sent through a
+// memConn and counted, and then scaled to the frame rate.
+func TestEstimateBandwidthMeasured(t *testing.T) {
+	const (
+		universes = 4
+		frames    = 25
+		fps       = 44.0
+	)
+	for _, slots := range []int{24, 512} {
+		s, c := testSender(t, Config{})
+		payload := make([]byte, slots)
+		for i := 0; i < frames; i++ {
+			for u := uint16(1); u <= universes; u++ {
+				var err error
+				if slots == 512 {
+					err = s.Send(0, Universe{Number: u})
+				} else {
+					// Send always carries a full universe, so shorter
+					// frames go out as an alternate START code.
+					err = s.SendStartCode(0, u, 0x17, payload)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		var packets, size int
+		for _, p := range c.packets() {
+			packets++
+			size += len(p.data)
+		}
+
+		want, err := EstimateBandwidth(universes, fps, slots)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pps := float64(packets) / frames * fps
+		bps := float64(size) / frames * fps
+		if pps != want.PacketsPerSecond || math.Abs(bps-want.PayloadBytesPerSecond) > 1e-6 {
+			t.Errorf("%d slots: measured %v packets and %v bytes a second, estimated %v and %v",
+				slots, pps, bps, want.PacketsPerSecond, want.PayloadBytesPerSecond)
+		}
+	}
+}
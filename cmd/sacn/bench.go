@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jagipson/e131"
+)
+
+func runBench(args []string) error {
+	fs := newFlags("bench")
+	n := fs.Int("n", 100000, "number of packets to build")
+	universes := fs.Int("universes", 1, "universes for the bandwidth estimate")
+	fps := fs.Float64("fps", 44, "frame rate for the bandwidth estimate")
+	slots := fs.Int("slots", 512, "slots per universe for the bandwidth estimate")
+	fs.Parse(args)
+
+	bw, err := e131.EstimateBandwidth(*universes, *fps, *slots)
+	if err != nil {
+		return err
+	}
+	cfg := e131.DefaultConfig()
+	u := e131.Universe{Number: 1}
+	start := time.Now()
+	for i := 0; i < *n; i++ {
+		u.Slots[0] = byte(i)
+		if _, err := cfg.DataPacket(e131.NoSync, uint8(i), 0, u); err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("built %d packets in %v (%.0f packets/s)\n", *n, elapsed, float64(*n)/elapsed.Seconds())
+	fmt.Printf("%d universes at %g fps: %.0f packets/s, %.0f payload bytes/s, %.2f Mbit/s on the wire\n",
+		*universes, *fps, bw.PacketsPerSecond, bw.PayloadBytesPerSecond, bw.WireBitsPerSecond/1e6)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jagipson/e131"
+)
+
+// parseRoutes parses universe mappings such as "1:101,2:102".
+func parseRoutes(s string) (map[uint16]uint16, error) {
+	routes := make(map[uint16]uint16)
+	for _, part := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(part, ":")
+		f, err1 := strconv.ParseUint(from, 10, 16)
+		t, err2 := strconv.ParseUint(to, 10, 16)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("Invalid route %q", part)
+		}
+		routes[uint16(f)] = uint16(t)
+	}
+	return routes, nil
+}
+
+func runBridge(args []string) error {
+	fs := newFlags("bridge")
+	route := fs.String("route", "1:2", "universe mappings from:to, e.g. 1:101,2:102")
+	name := fs.String("name", "sacn bridge", "source name")
+	priority := fs.Int("priority", 100, "universe priority, 0-200")
+	fs.Parse(args)
+
+	routes, err := parseRoutes(*route)
+	if err != nil {
+		return err
+	}
+	s, err := e131.New(e131.WithSourceName(*name), e131.WithPriority(*priority))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	m := e131.NewMerger(e131.HTP)
+	r := e131.NewReceiver(func(f e131.DataFrame) {
+		to, ok := routes[f.Universe.Number]
+		if !ok {
+			return
+		}
+		u := m.Update(f)
+		u.Number = to
+		if err := s.Send(0, u); err != nil {
+			log.Print(err)
+		}
+	})
+	r.IgnorePreview(true)
+	bus := e131.NewEventBus()
+	bus.Subscribe(e131.EventFilter{Kinds: e131.EventSourceLost}, func(e e131.Event) {
+		l := e.(e131.SourceLost)
+		u := m.Remove(l.Universe, l.CID)
+		u.Number = routes[l.Universe]
+		if err := s.Send(0, u); err != nil {
+			log.Print(err)
+		}
+	})
+	r.SetEventBus(bus)
+	defer r.Close()
+	for from := range routes {
+		if err := r.Join(from); err != nil {
+			return err
+		}
+	}
+	waitInterrupt()
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jagipson/e131"
+	uuid "github.com/satori/go.uuid"
+)
+
+// parseStream parses a stream such as "1" (the merged universe) or
+// "1@6ba7b810-9dad-11d1-80b4-00c04fd430c8" (one source of the universe).
+func parseStream(s string) (e131.Stream, error) {
+	number, cid, hasCID := strings.Cut(s, "@")
+	n, err := strconv.ParseUint(number, 10, 16)
+	if err != nil {
+		return e131.Stream{}, fmt.Errorf("Invalid stream %q", s)
+	}
+	stream := e131.Stream{Universe: uint16(n)}
+	if hasCID {
+		if stream.CID, err = uuid.FromString(cid); err != nil {
+			return e131.Stream{}, fmt.Errorf("Invalid stream %q: %v", s, err)
+		}
+	}
+	return stream, nil
+}
+
+func runCompare(args []string) error {
+	fs := newFlags("compare")
+	a := fs.String("a", "1", "reference stream: universe, or universe@CID for one source")
+	b := fs.String("b", "2", "stream compared with the reference, in the same form")
+	tolerance := fs.Int("tolerance", 0, "level difference treated as equal, 0-255")
+	interval := fs.Duration("interval", 5*time.Second, "time between reports")
+	worst := fs.Int("worst", 8, "number of most divergent channels to report")
+	fs.Parse(args)
+
+	sa, err := parseStream(*a)
+	if err != nil {
+		return err
+	}
+	sb, err := parseStream(*b)
+	if err != nil {
+		return err
+	}
+	if *tolerance < 0 || *tolerance > 255 {
+		return fmt.Errorf("Invalid tolerance %d", *tolerance)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("Invalid interval %v", *interval)
+	}
+
+	r := e131.NewReceiver(func(e131.DataFrame) {})
+	defer r.Close()
+	c, err := e131.NewComparator(r, sa, sb, uint8(*tolerance))
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report(c.Stats(), *worst)
+		case <-interrupt:
+			report(c.Stats(), *worst)
+			return nil
+		}
+	}
+}
+
+// report prints a summary of d and its n most often mismatched channels.
+func report(d e131.Divergence, n int) {
+	pct := 0.0
+	if d.Comparisons > 0 {
+		pct = 100 * float64(d.Divergent) / float64(d.Comparisons)
+	}
+	fmt.Printf("%s: %d comparisons, %d divergent (%.2f%%)\n",
+		time.Since(d.Since).Round(time.Second), d.Comparisons, d.Divergent, pct)
+
+	var channels []int
+	for ch, cd := range d.Channels {
+		if cd.Mismatches > 0 {
+			channels = append(channels, ch)
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return d.Channels[channels[i]].Mismatches > d.Channels[channels[j]].Mismatches
+	})
+	if len(channels) > n {
+		channels = channels[:n]
+	}
+	for _, ch := range channels {
+		cd := d.Channels[ch]
+		fmt.Printf("  slot %3d: %d mismatches, max delta %d, mean delta %.2f\n",
+			ch+1, cd.Mismatches, cd.MaxDelta, d.MeanDelta(ch))
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jagipson/e131"
+)
+
+func runDiscover(args []string) error {
+	fs := newFlags("discover")
+	wait := fs.Duration("wait", e131.DiscoveryInterval+time.Second, "how long to listen; sources advertise every 10s")
+	fs.Parse(args)
+
+	l, err := e131.NewDiscoveryListener()
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	time.Sleep(*wait)
+
+	for _, src := range l.Sources() {
+		fmt.Printf("%s %q %v\n", src.CID, src.SourceName, src.Universes)
+	}
+	return nil
+}
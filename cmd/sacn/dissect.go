@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/jagipson/e131"
+)
+
+// readLabels reads channel labels from a CSV file with the columns universe,
+// address, name, fixture, notes and, optionally, watts. Addresses are DMX
+// addresses, 1-512. A first row starting with "universe" is a header.
+func readLabels(name string) (e131.Labels, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 && records[0][0] == "universe" {
+		records = records[1:]
+	}
+
+	labels := make(e131.Labels)
+	for i, rec := range records {
+		if len(rec) < 5 || len(rec) > 6 {
+			return nil, fmt.Errorf("Invalid label on line %d of %s: want 5 or 6 fields", i+1, name)
+		}
+		universe, err1 := strconv.ParseUint(rec[0], 10, 16)
+		address, err2 := strconv.Atoi(rec[1])
+		if err1 != nil || err2 != nil || address < 1 || address > 512 {
+			return nil, fmt.Errorf("Invalid label address %q/%q in %s", rec[0], rec[1], name)
+		}
+		l := e131.ChannelLabel{Name: rec[2], Fixture: rec[3], Notes: rec[4]}
+		if len(rec) == 6 && rec[5] != "" {
+			if l.Watts, err = strconv.ParseFloat(rec[5], 64); err != nil {
+				return nil, fmt.Errorf("Invalid label wattage %q in %s", rec[5], name)
+			}
+		}
+		labels[e131.ChannelAddr{Universe: uint16(universe), Channel: address - 1}] = l
+	}
+	return labels, nil
+}
+
+func runDissect(args []string) error {
+	fs := newFlags("dissect")
+	labelFile := fs.String("labels", "", "CSV file of channel labels: universe,address,name,fixture,notes[,watts]")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: sacn dissect [-labels file] [file ...]")
+		fmt.Fprintln(fs.Output(), "Each file holds one packet; with no files the packet is read from standard input.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var labels e131.Labels
+	if *labelFile != "" {
+		var err error
+		if labels, err = readLabels(*labelFile); err != nil {
+			return err
+		}
+	}
+
+	if fs.NArg() == 0 {
+		packet, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return e131.AnnotateLabels(os.Stdout, packet, labels)
+	}
+	for i, name := range fs.Args() {
+		packet, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s:\n", name)
+		if err := e131.AnnotateLabels(os.Stdout, packet, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"github.com/jagipson/e131"
+)
+
+// The fixtures are built from the specification layout with a fixed CID and
+// source name so the output is byte-for-byte reproducible. The name is the one
+// the golden files were first written with.
+var (
+	fixtureCID  = []byte{0x5a, 0x1f, 0x0e, 0x2b, 0x9c, 0x3d, 0x4e, 0x8f, 0xa0, 0xb1, 0xc2, 0xd3, 0xe4, 0xf5, 0x06, 0x17}
+	fixtureName = "sacn-genfixtures"
+)
+
+// Root and framing layer vectors.
+var (
+	vectorRootData     = []byte{0x00, 0x00, 0x00, 0x04}
+	vectorRootExtended = []byte{0x00, 0x00, 0x00, 0x08}
+	vectorRootDraft    = []byte{0x00, 0x00, 0x00, 0x03}
+	vectorFrameData    = []byte{0x00, 0x00, 0x00, 0x02}
+	vectorFrameSync    = []byte{0x00, 0x00, 0x00, 0x01}
+	vectorFrameDisc    = []byte{0x00, 0x00, 0x00, 0x02}
+	vectorDiscList     = []byte{0x00, 0x00, 0x00, 0x01}
+)
+
+// pdu returns a PDU with flags and length preceding vector and body.
+func pdu(vector, body []byte) []byte {
+	data := []byte{0x00, 0x00}
+	data = append(data, vector...)
+	data = append(data, body...)
+	binary.BigEndian.PutUint16(data, uint16(len(data))|0x7000)
+	return data
+}
+
+// rootPacket wraps a framing layer PDU in a root layer.
+func rootPacket(rootVector, framing []byte) []byte {
+	data := []byte{0x00, 0x10, 0x00, 0x00}
+	data = append(data, "ASC-E1.17\x00\x00\x00"...)
+	return append(data, pdu(rootVector, append(append([]byte{}, fixtureCID...), framing...))...)
+}
+
+// paddedName returns the fixture source name null-padded to size bytes.
+func paddedName(size int) []byte {
+	b := make([]byte, size)
+	copy(b, fixtureName)
+	return b
+}
+
+func u16(v uint16) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
+
+// dmp returns a DMP layer carrying startCode followed by slots.
+func dmp(startCode byte, slots []byte) []byte {
+	body := []byte{0xa1, 0x00, 0x00, 0x00, 0x01}
+	body = append(body, u16(uint16(len(slots)+1))...)
+	body = append(body, startCode)
+	body = append(body, slots...)
+	return pdu([]byte{0x02}, body)
+}
+
+func dataPacket(universe, syncAddr uint16, options, startCode byte, slots []byte) []byte {
+	header := paddedName(64)
+	header = append(header, 100)
+	header = append(header, u16(syncAddr)...)
+	header = append(header, 1, options)
+	header = append(header, u16(universe)...)
+	return rootPacket(vectorRootData, pdu(vectorFrameData, append(header, dmp(startCode, slots)...)))
+}
+
+// draftDataPacket uses the pre-ratification layout: root vector 3, a 32-byte
+// source name and no synchronization address, options or reserved fields.
+func draftDataPacket(universe uint16, slots []byte) []byte {
+	header := paddedName(32)
+	header = append(header, 100, 1)
+	header = append(header, u16(universe)...)
+	return rootPacket(vectorRootDraft, pdu(vectorFrameData, append(header, dmp(0x00, slots)...)))
+}
+
+func syncPacket(syncAddr uint16) []byte {
+	body := []byte{1}
+	body = append(body, u16(syncAddr)...)
+	body = append(body, 0x00, 0x00)
+	return rootPacket(vectorRootExtended, pdu(vectorFrameSync, body))
+}
+
+func discPacket(page, lastPage byte, universes []uint16) []byte {
+	body := []byte{page, lastPage}
+	for _, u := range universes {
+		body = append(body, u16(u)...)
+	}
+	framing := append(paddedName(64), 0x00, 0x00, 0x00, 0x00)
+	framing = append(framing, pdu(vectorDiscList, body)...)
+	return rootPacket(vectorRootExtended, pdu(vectorFrameDisc, framing))
+}
+
+// ramp returns n slots counting up from 1.
+func ramp(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i + 1)
+	}
+	return b
+}
+
+// fill returns n slots set to v.
+func fill(n int, v byte) []byte {
+	return bytes.Repeat([]byte{v}, n)
+}
+
+// universeRange returns the universes first through last inclusive.
+func universeRange(first, last uint16) []uint16 {
+	var u []uint16
+	for i := first; i <= last; i++ {
+		u = append(u, i)
+	}
+	return u
+}
+
+// runGenfixtures writes a directory of canonical E1.31 packets, one file per
+// packet, for use as the golden files in testdata/golden. Each .bin file is
+// accompanied by a .txt file annotating its fields.
+func runGenfixtures(args []string) error {
+	fs := newFlags("genfixtures")
+	out := fs.String("out", "fixtures", "directory to write fixtures into")
+	fs.Parse(args)
+
+	fixtures := map[string][]byte{
+		"ratified/data-512.bin":            dataPacket(1, 0, 0x00, 0x00, ramp(512)),
+		"ratified/data-1.bin":              dataPacket(1, 0, 0x00, 0x00, ramp(1)),
+		"ratified/data-0.bin":              dataPacket(1, 0, 0x00, 0x00, nil),
+		"ratified/data-universe-63999.bin": dataPacket(63999, 0, 0x00, 0x00, ramp(512)),
+		"ratified/data-preview.bin":        dataPacket(1, 0, 0x80, 0x00, ramp(512)),
+		"ratified/data-terminated.bin":     dataPacket(1, 0, 0x40, 0x00, ramp(512)),
+		"ratified/data-force-sync.bin":     dataPacket(1, 7, 0x20, 0x00, ramp(512)),
+		"ratified/data-priority-dd.bin":    dataPacket(1, 0, 0x00, 0xdd, fill(512, 100)),
+		"ratified/sync.bin":                syncPacket(7),
+		"ratified/discovery-empty.bin":     discPacket(0, 0, nil),
+		"ratified/discovery-512.bin":       discPacket(0, 1, universeRange(1, 512)),
+		"ratified/discovery-page-1.bin":    discPacket(1, 1, universeRange(513, 600)),
+		"draft/data-512.bin":               draftDataPacket(1, ramp(512)),
+	}
+
+	for path, b := range fixtures {
+		path = filepath.Join(*out, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, b, 0644); err != nil {
+			return err
+		}
+
+		var txt bytes.Buffer
+		if err := e131.Annotate(&txt, b); err != nil {
+			return err
+		}
+		txtPath := path[:len(path)-len(filepath.Ext(path))] + ".txt"
+		if err := os.WriteFile(txtPath, txt.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
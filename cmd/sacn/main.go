@@ -0,0 +1,108 @@
+// Command sacn is a toolbox for working with sACN (E1.31) networks.
+//
+// Usage:
+//
+//	sacn <command> [flags]
+//
+// The commands are:
+//
+//	send        send levels on universes
+//	monitor     print data frames as they arrive
+//	record      record raw packets to a file
+//	play        replay a recording with its original timing
+//	bridge      receive universes and resend them under other numbers
+//	discover    list sources found through universe discovery
+//	dissect     annotate the fields of packets stored in files
+//	bench       measure packet building speed and estimate bandwidth
+//	compare     report how two streams differ channel by channel
+//	soak        send and receive back self-verifying frames for hours
+//	genfixtures write the golden packet files for the tests
+//
+// Run "sacn <command> -h" for the flags of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command is a subcommand taking its own arguments.
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"send":        {"send levels on universes", runSend},
+	"monitor":     {"print data frames as they arrive", runMonitor},
+	"record":      {"record raw packets to a file", runRecord},
+	"play":        {"replay a recording with its original timing", runPlay},
+	"bridge":      {"receive universes and resend them under other numbers", runBridge},
+	"discover":    {"list sources found through universe discovery", runDiscover},
+	"dissect":     {"annotate the fields of packets stored in files", runDissect},
+	"bench":       {"measure packet building speed and estimate bandwidth", runBench},
+	"compare":     {"report how two streams differ channel by channel", runCompare},
+	"soak":        {"send and receive back self-verifying frames for hours", runSoak},
+	"genfixtures": {"write the golden packet files for the tests", runGenfixtures},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: sacn <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", name, commands[name].summary)
+	}
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sacn: ")
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newFlags returns the flag set for the command name.
+func newFlags(name string) *flag.FlagSet {
+	return flag.NewFlagSet("sacn "+name, flag.ExitOnError)
+}
+
+// parseUniverses parses a list of universes such as "1,3,10-12".
+func parseUniverses(s string) ([]uint16, error) {
+	var universes []uint16
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.ParseUint(lo, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid universe %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.ParseUint(hi, 10, 16); err != nil || last < first {
+				return nil, fmt.Errorf("Invalid universe range %q", part)
+			}
+		}
+		for u := first; u <= last; u++ {
+			universes = append(universes, uint16(u))
+		}
+	}
+	return universes, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/jagipson/e131"
+)
+
+func runMonitor(args []string) error {
+	fs := newFlags("monitor")
+	universes := fs.String("universe", "1", "universes to monitor, e.g. 1,3,10-12")
+	width := fs.Int("slots", 16, "number of slots to print per frame")
+	fs.Parse(args)
+
+	numbers, err := parseUniverses(*universes)
+	if err != nil {
+		return err
+	}
+	if *width < 0 || *width > 512 {
+		return fmt.Errorf("Invalid slot count %d", *width)
+	}
+	var mu sync.Mutex
+	r := e131.NewReceiver(func(f e131.DataFrame) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("%5d %-20q pri %3d seq %3d opts %-16v start %02x  % x\n",
+			f.Universe.Number, f.SourceName, f.Priority, f.Sequence,
+			e131.Options(f.Options), f.StartCode, f.Universe.Slots[:*width])
+	})
+	bus := e131.NewEventBus()
+	bus.Subscribe(e131.EventFilter{}, func(e e131.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e := e.(type) {
+		case e131.SourceFound:
+			fmt.Printf("%5d %-20q found\n", e.Universe, e.SourceName)
+		case e131.SourceLost:
+			fmt.Printf("%5d %-20q lost (terminated %v)\n", e.Universe, e.SourceName, e.Terminated)
+		case e131.SyncLost:
+			fmt.Printf("%5d synchronization lost\n", e.SyncAddr)
+		case e131.ParseError:
+			fmt.Printf("%5d bad packet from %v: %v\n", e.Universe, e.Addr, e.Err)
+		case e131.SocketError:
+			fmt.Printf("      socket error: %v\n", e.Err)
+		}
+	})
+	r.SetEventBus(bus)
+	defer r.Close()
+	for _, n := range numbers {
+		if err := r.Join(n); err != nil {
+			return err
+		}
+	}
+	waitInterrupt()
+	return nil
+}
+
+// waitInterrupt blocks until the process is interrupted.
+func waitInterrupt() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	<-c
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/jagipson/e131"
+)
+
+// destination returns the universe whose multicast group packet belongs on.
+func destination(packet []byte) (uint16, bool) {
+	switch e131.Classify(packet) {
+	case e131.PacketData:
+		f, err := e131.ParseDataPacket(packet)
+		return f.Universe.Number, err == nil
+	case e131.PacketSync:
+		f, err := e131.ParseSyncPacket(packet)
+		return f.SyncAddr, err == nil
+	case e131.PacketDiscovery:
+		return e131.DiscoveryUniverse, true
+	}
+	return 0, false
+}
+
+func runPlay(args []string) error {
+	fs := newFlags("play")
+	in := fs.String("in", "sacn.rec", "recording to play")
+	loop := fs.Bool("loop", false, "repeat the recording until interrupted")
+	fs.Parse(args)
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		if err := play(conn, *in); err != nil || !*loop {
+			return err
+		}
+	}
+}
+
+// play sends the packets recorded in the file name through conn.
+func play(conn *net.UDPConn, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	h, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	if !h.Start.IsZero() {
+		log.Printf("playing %s, recorded on %s at %s", name, h.Host, h.Start.Format(time.RFC3339Nano))
+	}
+
+	start := time.Now()
+	for {
+		offset, packet, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		universe, ok := destination(packet)
+		if !ok {
+			continue
+		}
+		time.Sleep(time.Until(start.Add(offset)))
+		if _, err := conn.WriteTo(packet, e131.MulticastAddr(universe)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jagipson/e131"
+)
+
+// A recording is a header followed by a sequence of records. The header is
+// recordMagic, the 8-byte big-endian Unix time in nanoseconds at which the
+// recording started, a 1-byte length and that many bytes naming the host that
+// made it, so that recordings from several hosts can be aligned. Each record
+// is an 8-byte big-endian offset in nanoseconds from the start of the
+// recording, a 2-byte big-endian length and that many bytes of packet.
+// Recordings made before the header was added start directly with a record.
+
+// recordMagic starts the header of a recording, and carries its version.
+const recordMagic = "sACNrec\x01"
+
+// recordHeader describes a recording.
+type recordHeader struct {
+	// Start is when the recording started, or zero if it has no header.
+	Start time.Time
+	// Host is the host that made the recording.
+	Host string
+}
+
+// writeHeader writes h to w. Host names longer than 255 bytes are truncated.
+func writeHeader(w io.Writer, h recordHeader) error {
+	host := h.Host
+	if len(host) > 255 {
+		host = host[:255]
+	}
+	b := append([]byte(recordMagic), make([]byte, 9)...)
+	binary.BigEndian.PutUint64(b[len(recordMagic):], uint64(h.Start.UnixNano()))
+	b[len(b)-1] = byte(len(host))
+	b = append(b, host...)
+	_, err := w.Write(b)
+	return err
+}
+
+// readHeader reads the header of the recording r, returning a zero header for
+// a recording without one.
+func readHeader(r *bufio.Reader) (recordHeader, error) {
+	magic, err := r.Peek(len(recordMagic))
+	if err == io.EOF || string(magic) != recordMagic {
+		return recordHeader{}, nil
+	}
+	if err != nil {
+		return recordHeader{}, err
+	}
+	var b [len(recordMagic) + 9]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return recordHeader{}, unexpected(err)
+	}
+	host := make([]byte, b[len(b)-1])
+	if _, err := io.ReadFull(r, host); err != nil {
+		return recordHeader{}, unexpected(err)
+	}
+	start := time.Unix(0, int64(binary.BigEndian.Uint64(b[len(recordMagic):])))
+	return recordHeader{Start: start, Host: string(host)}, nil
+}
+
+// unexpected turns io.EOF, from a read in the middle of a recording, into
+// io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// writeRecord appends one packet received at offset to w.
+func writeRecord(w io.Writer, offset time.Duration, packet []byte) error {
+	var header [10]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(offset))
+	binary.BigEndian.PutUint16(header[8:], uint16(len(packet)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(packet)
+	return err
+}
+
+// readRecord reads the next packet from r. It returns io.EOF at the end of
+// the recording.
+func readRecord(r io.Reader) (time.Duration, []byte, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	packet := make([]byte, binary.BigEndian.Uint16(header[8:]))
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return 0, nil, unexpected(err)
+	}
+	return time.Duration(binary.BigEndian.Uint64(header[:8])), packet, nil
+}
+
+func runRecord(args []string) error {
+	fs := newFlags("record")
+	universes := fs.String("universe", "1", "universes to record, e.g. 1,3,10-12")
+	out := fs.String("out", "sacn.rec", "file to write")
+	discovery := fs.Bool("discovery", false, "also record universe discovery")
+	host := fs.String("host", "", "host name to store in the recording (default the system host name)")
+	fs.Parse(args)
+
+	numbers, err := parseUniverses(*universes)
+	if err != nil {
+		return err
+	}
+	if *discovery {
+		numbers = append(numbers, e131.DiscoveryUniverse)
+	}
+	if *host == "" {
+		if *host, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+		wg       sync.WaitGroup
+		conns    []*net.UDPConn
+	)
+	start := time.Now()
+	if err := writeHeader(w, recordHeader{Start: start, Host: *host}); err != nil {
+		return err
+	}
+	for _, n := range numbers {
+		conn, err := net.ListenMulticastUDP("udp4", nil, e131.MulticastAddr(n))
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		wg.Add(1)
+		// Every socket bound to the sACN port receives all the groups
+		// joined on the host, so each keeps only its own universe.
+		go func(n uint16) {
+			defer wg.Done()
+			buf := make([]byte, 1500)
+			for {
+				k, _, err := conn.ReadFrom(buf)
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				if err != nil {
+					continue
+				}
+				if u, ok := destination(buf[:k]); !ok || u != n {
+					continue
+				}
+				mu.Lock()
+				if writeErr == nil {
+					writeErr = writeRecord(w, time.Since(start), buf[:k])
+				}
+				mu.Unlock()
+			}
+		}(n)
+	}
+
+	waitInterrupt()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	wg.Wait()
+	if writeErr != nil {
+		return writeErr
+	}
+	return w.Flush()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jagipson/e131"
+)
+
+// parseLevels parses slot assignments such as "1=255,2-4=128" into slots,
+// numbering slots from 1 as DMX addresses are.
+func parseLevels(s string, slots *[512]byte) error {
+	if s == "" {
+		return nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		addrs, level, ok := strings.Cut(part, "=")
+		v, err := strconv.ParseUint(level, 10, 8)
+		if !ok || err != nil {
+			return fmt.Errorf("Invalid level %q", part)
+		}
+		lo, hi, isRange := strings.Cut(addrs, "-")
+		first, err := strconv.Atoi(lo)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(hi)
+		}
+		if err != nil || first < 1 || last > 512 || last < first {
+			return fmt.Errorf("Invalid address %q", addrs)
+		}
+		for a := first; a <= last; a++ {
+			slots[a-1] = byte(v)
+		}
+	}
+	return nil
+}
+
+func runSend(args []string) error {
+	fs := newFlags("send")
+	universes := fs.String("universe", "1", "universes to send, e.g. 1,3,10-12")
+	all := fs.Int("all", 0, "level for every slot")
+	levels := fs.String("levels", "", "levels for particular addresses, e.g. 1=255,2-4=128")
+	name := fs.String("name", "sacn", "source name")
+	priority := fs.Int("priority", 100, "universe priority, 0-200")
+	fps := fs.Float64("fps", 0, "frames per second; 0 sends once")
+	duration := fs.Duration("duration", 0, "how long to keep sending at -fps; 0 is forever")
+	fs.Parse(args)
+
+	numbers, err := parseUniverses(*universes)
+	if err != nil {
+		return err
+	}
+	if *all < 0 || *all > 255 {
+		return fmt.Errorf("Invalid level %d", *all)
+	}
+	var slots [512]byte
+	for i := range slots {
+		slots[i] = byte(*all)
+	}
+	if err := parseLevels(*levels, &slots); err != nil {
+		return err
+	}
+	s, err := e131.New(e131.WithSourceName(*name), e131.WithPriority(*priority))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	sendAll := func() error {
+		for _, n := range numbers {
+			if err := s.Send(0, e131.Universe{Slots: slots, Number: n}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if *fps <= 0 {
+		return sendAll()
+	}
+	tick := time.NewTicker(time.Duration(float64(time.Second) / *fps))
+	defer tick.Stop()
+	var end <-chan time.Time
+	if *duration > 0 {
+		end = time.After(*duration)
+	}
+	for {
+		if err := sendAll(); err != nil {
+			return err
+		}
+		select {
+		case <-tick.C:
+		case <-end:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+// Stream selects the data compared by a Comparator: a universe and, unless
+// CID is the zero UUID, only the source with that CID. Without a CID the
+// universe's HTP-merged output is used.
+type Stream struct {
+	Universe uint16
+	CID      uuid.UUID
+}
+
+// ChannelDivergence counts how one channel differed between two streams.
+type ChannelDivergence struct {
+	// Mismatches is the number of comparisons in which the channel's levels
+	// differed by more than the tolerance.
+	Mismatches uint64
+	// MaxDelta is the largest difference seen.
+	MaxDelta uint8
+	// TotalDelta is the sum of the differences, for computing the mean.
+	TotalDelta uint64
+}
+
+// Divergence is a summary of how two streams have differed.
+type Divergence struct {
+	// Since is when counting started: creation or the last Reset.
+	Since time.Time
+	// Comparisons is the number of times the streams were compared, once
+	// per update of either stream after both have been received.
+	Comparisons uint64
+	// Divergent is the number of comparisons in which any channel differed
+	// by more than the tolerance.
+	Divergent uint64
+	// LastDivergent is when a comparison last diverged, or the zero time.
+	LastDivergent time.Time
+	// Channels holds the per-channel counts, indexed like Universe.Slots.
+	Channels [512]ChannelDivergence
+}
+
+// MeanDelta returns the mean difference of channel over all comparisons.
+func (d *Divergence) MeanDelta(channel int) float64 {
+	if d.Comparisons == 0 {
+		return 0
+	}
+	return float64(d.Channels[channel].TotalDelta) / float64(d.Comparisons)
+}
+
+// Comparator compares two live streams channel by channel, for example to
+// check that a replacement gateway reproduces the output of the one it
+// replaces. Each time either stream updates, its latest levels are compared
+// with the other's.
+type Comparator struct {
+	tolerance uint8
+	subs      [2]*Subscription
+
+	mu    sync.Mutex
+	have  [2]bool
+	slots [2][512]byte
+	stats Divergence
+}
+
+// NewComparator subscribes to streams a and b on r and starts comparing
+// them. Channels whose levels differ by no more than tolerance are treated
+// as equal, allowing for rounding in conversions. Close stops it.
+func NewComparator(r *Receiver, a, b Stream, tolerance uint8) (*Comparator, error) {
+	c := &Comparator{tolerance: tolerance}
+	c.stats.Since = time.Now()
+	for i, s := range [2]Stream{a, b} {
+		sub, err := c.subscribe(r, i, s)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.subs[i] = sub
+	}
+	return c, nil
+}
+
+// subscribe feeds stream s to side i of the comparison.
+func (c *Comparator) subscribe(r *Receiver, i int, s Stream) (*Subscription, error) {
+	if s.CID == uuid.Nil {
+		return r.SubscribeMerged(s.Universe, HTP, func(u Universe) {
+			c.update(i, &u.Slots, time.Now())
+		})
+	}
+	return r.Subscribe(s.Universe, func(f DataFrame) {
+		if f.CID == s.CID && f.StartCode == NullStartCode {
+			c.update(i, &f.Universe.Slots, time.Now())
+		}
+	})
+}
+
+// update records new levels for side i and compares the two sides.
+func (c *Comparator) update(i int, slots *[512]byte, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slots[i] = *slots
+	c.have[i] = true
+	if !c.have[0] || !c.have[1] {
+		return
+	}
+
+	c.stats.Comparisons++
+	divergent := false
+	for ch := range c.slots[0] {
+		a, b := c.slots[0][ch], c.slots[1][ch]
+		delta := a - b
+		if b > a {
+			delta = b - a
+		}
+		cd := &c.stats.Channels[ch]
+		cd.TotalDelta += uint64(delta)
+		if delta > cd.MaxDelta {
+			cd.MaxDelta = delta
+		}
+		if delta > c.tolerance {
+			cd.Mismatches++
+			divergent = true
+		}
+	}
+	if divergent {
+		c.stats.Divergent++
+		c.stats.LastDivergent = now
+	}
+}
+
+// Stats returns the divergence counted so far.
+func (c *Comparator) Stats() Divergence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Reset clears the counts, keeping the latest levels of both streams.
+func (c *Comparator) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = Divergence{Since: time.Now()}
+}
+
+// Close cancels the Comparator's subscriptions. The Receiver stays open.
+func (c *Comparator) Close() error {
+	var err error
+	for _, sub := range c.subs {
+		if sub == nil {
+			continue
+		}
+		if cerr := sub.Cancel(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
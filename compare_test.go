@@ -0,0 +1,62 @@
+package e131
+
+import (
+	"testing"
+)
+
+func TestComparator(t *testing.T) {
+	r, c := testReceiver(t, func(DataFrame) {})
+	a, b := testSource(), testSource()
+	cmp, err := NewComparator(r, Stream{Universe: 1, CID: a.CID}, Stream{Universe: 2}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmp.Close()
+	// Subscriptions are called in order, so one made after the
+	// Comparator's reports when it has seen each frame.
+	seen := make(chan DataFrame, 8)
+	for _, u := range []uint16{1, 2} {
+		if _, err := r.Subscribe(u, func(f DataFrame) { seen <- f }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	send := func(cfg Config, universe uint16, seq, level uint8) {
+		t.Helper()
+		c.deliver(levelPacket(t, cfg, universe, seq, level), sourceAddr)
+		nextFrame(t, seen)
+	}
+
+	send(a, 1, 0, 10)
+	if d := cmp.Stats(); d.Comparisons != 0 {
+		t.Errorf("%d comparisons before both streams arrived", d.Comparisons)
+	}
+	send(b, 1, 0, 50) // another source on the compared universe
+	send(b, 2, 0, 11)
+	send(b, 2, 1, 20)
+	d := cmp.Stats()
+	if d.Comparisons != 2 || d.Divergent != 1 || d.LastDivergent.IsZero() {
+		t.Errorf("got %d comparisons, %d divergent, want 2 and 1", d.Comparisons, d.Divergent)
+	}
+	if ch := d.Channels[0]; ch.Mismatches != 1 || ch.MaxDelta != 10 || ch.TotalDelta != 11 {
+		t.Errorf("channel 1 counted %+v", ch)
+	}
+	if m := d.MeanDelta(0); m != 5.5 {
+		t.Errorf("mean delta %v, want 5.5", m)
+	}
+	if d.Channels[1] != (ChannelDivergence{}) {
+		t.Errorf("equal channel counted %+v", d.Channels[1])
+	}
+
+	cmp.Reset()
+	send(a, 1, 1, 20)
+	if d := cmp.Stats(); d.Comparisons != 1 || d.Divergent != 0 {
+		t.Errorf("after Reset got %d comparisons, %d divergent, want 1 and 0", d.Comparisons, d.Divergent)
+	}
+	if err := cmp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	send(a, 1, 2, 0)
+	if d := cmp.Stats(); d.Comparisons != 1 {
+		t.Errorf("compared %d times in all, want no comparison after Close", d.Comparisons)
+	}
+}
@@ -0,0 +1,456 @@
+package e131
+
+import (
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"os"
+	"time"
+)
+
+// Config describes the sACN source a Sender transmits as. Each Sender has
+// its own copy, so several independent sources can run in one process.
+type Config struct {
+	// CID is the component identifier carried in every packet. It should be
+	// stable for the lifetime of the source. If it is the zero UUID a new
+	// one is generated.
+	CID uuid.UUID
+	// SourceName is the user-assigned name, 1-63 bytes.
+	SourceName string
+	// Priority is the DMX message priority, 0-200.
+	Priority uint8
+	// SyncAddr is the synchronization universe that data packets are
+	// addressed to by default. NoSync, the zero value, sends unsynchronized
+	// data that receivers act on immediately.
+	SyncAddr uint16
+	// ForceSync sets the force synchronization option on synchronized data
+	// packets, telling receivers to resume acting on data at once, rather
+	// than holding their output, if synchronization packets stop arriving.
+	ForceSync bool
+	// IPv6 sends to the IPv6 multicast groups from an IPv6 socket instead
+	// of using IPv4.
+	IPv6 bool
+	// Interface is the network interface multicast is sent from. If nil
+	// the system chooses.
+	Interface *net.Interface
+	// Discovery makes a Sender advertise the universes it is sending on
+	// the discovery universe every DiscoveryInterval, as E1.31 requires.
+	Discovery bool
+	// AdvertiseInterval, if positive, replaces DiscoveryInterval as the
+	// period of discovery advertisements. It must be 0 or at least
+	// MinAdvertiseInterval.
+	AdvertiseInterval time.Duration
+	// AdvertiseChanges makes a Sender with Discovery also advertise as
+	// soon as a universe starts or stops being sent, so that controllers
+	// see topology changes without waiting for the next period.
+	AdvertiseChanges bool
+	// Unicast lists, per universe, the addresses that universe is sent to
+	// instead of its multicast group. Universes without an entry are
+	// multicast.
+	Unicast map[uint16][]*net.UDPAddr
+	// KeepAlive, if positive, makes a Sender retransmit the last packet of
+	// each universe whenever it has sent nothing for that long, so that
+	// receivers do not time the source out while the data is unchanged.
+	// E1.31 receivers time out after 2.5s; around 800ms is typical. It must
+	// be 0 or at least MinKeepAlive.
+	KeepAlive time.Duration
+	// UniverseKeepAlive overrides KeepAlive for the universes it lists,
+	// for receivers that time out sooner than E1.31 requires. 0 turns
+	// keep-alive off for a universe.
+	UniverseKeepAlive map[uint16]time.Duration
+	// DestinationKeepAlive sets a keep-alive interval for unicast
+	// destinations, keyed by the address's String form, such as
+	// "10.0.0.5:5568". A universe sent to several destinations is kept
+	// alive at the shortest of their intervals and its own, so that each
+	// destination hears from it at least as often as it asks.
+	DestinationKeepAlive map[string]time.Duration
+	// Withhold, if positive, keeps a Sender from transmitting a universe
+	// until MarkInitialized is called for it or Withhold has passed since
+	// the Sender was created, so that receivers never see zeroed or
+	// partially written frames at startup.
+	Withhold time.Duration
+	// Limits caps the traffic the Sender will transmit.
+	Limits Limits
+	// Labels attaches human-readable metadata to channels for diagnostic
+	// output. It is optional.
+	Labels Labels
+}
+
+// ChannelAddr identifies one channel: Channel is the index into the
+// universe's Slots, i.e. the DMX address minus one.
+type ChannelAddr struct {
+	Universe uint16
+	Channel  int
+}
+
+// ChannelLabel is human-readable metadata for a channel.
+type ChannelLabel struct {
+	Name    string
+	Fixture string
+	Notes   string
+	// Watts is the power the channel's load draws at full level, used by
+	// EnergyMeter. Zero means unknown.
+	Watts float64
+}
+
+// String returns the label's name and fixture, for example "Pan (Spot 1)".
+func (l ChannelLabel) String() string {
+	if l.Fixture == "" {
+		return l.Name
+	}
+	return l.Name + " (" + l.Fixture + ")"
+}
+
+// Labels maps channels to their metadata.
+type Labels map[ChannelAddr]ChannelLabel
+
+// MinKeepAlive is the shortest Config.KeepAlive. Retransmitting unchanged
+// data more often than DMX512's fastest refresh, 44 packets a second, serves
+// no purpose.
+const MinKeepAlive = 25 * time.Millisecond
+
+// MinAdvertiseInterval is the shortest Config.AdvertiseInterval.
+const MinAdvertiseInterval = time.Second
+
+// NoSync is the synchronization address of data that is not synchronized.
+const NoSync uint16 = 0
+
+// Synchronized reports whether the Config sends synchronized data by default.
+func (c Config) Synchronized() bool {
+	return c.SyncAddr != NoSync
+}
+
+// DefaultConfig returns a Config with a new CID, the source name go131-[PID],
+// priority 100 and universe discovery on.
+func DefaultConfig() Config {
+	return Config{
+		CID:        uuid.NewV4(),
+		SourceName: fmt.Sprintf("go131-%d", os.Getpid()),
+		Priority:   100,
+		Discovery:  true,
+	}
+}
+
+// check returns an error if the source name or priority are out of range.
+func (c Config) check() error {
+	if err := checkSourceName(c.SourceName); err != nil {
+		return err
+	}
+	if err := checkSyncAddr(c.SyncAddr); err != nil {
+		return err
+	}
+	if err := checkKeepAlive(c.KeepAlive); err != nil {
+		return err
+	}
+	for universe, interval := range c.UniverseKeepAlive {
+		if err := checkUniverse(universe); err != nil {
+			return err
+		}
+		if err := checkKeepAlive(interval); err != nil {
+			return err
+		}
+	}
+	for _, interval := range c.DestinationKeepAlive {
+		if err := checkKeepAlive(interval); err != nil {
+			return err
+		}
+	}
+	if c.AdvertiseInterval < 0 || c.AdvertiseInterval > 0 && c.AdvertiseInterval < MinAdvertiseInterval {
+		return errorf(ErrInvalidConfig, "Unable to set AdvertiseInterval (out of bounds)")
+	}
+	if c.Withhold < 0 {
+		return errorf(ErrInvalidConfig, "Unable to set Withhold (out of bounds)")
+	}
+	if err := c.Limits.check(); err != nil {
+		return err
+	}
+	for universe, addrs := range c.Unicast {
+		if err := checkUnicast(universe, addrs); err != nil {
+			return err
+		}
+	}
+	return checkPriority(int(c.Priority))
+}
+
+// Option adjusts the Config used by New.
+type Option func(*Config) error
+
+// WithCID sets the component identifier.
+func WithCID(cid uuid.UUID) Option {
+	return func(c *Config) error {
+		c.CID = cid
+		return nil
+	}
+}
+
+// WithSourceName sets the user-assigned source name.
+func WithSourceName(s string) Option {
+	return func(c *Config) error {
+		if err := checkSourceName(s); err != nil {
+			return err
+		}
+		c.SourceName = s
+		return nil
+	}
+}
+
+// WithPriority sets the DMX message priority, 0-200.
+func WithPriority(i int) Option {
+	return func(c *Config) error {
+		if err := checkPriority(i); err != nil {
+			return err
+		}
+		c.Priority = uint8(i)
+		return nil
+	}
+}
+
+// WithSync makes data packets synchronized on the universe syncAddr by
+// default.
+func WithSync(syncAddr uint16) Option {
+	return func(c *Config) error {
+		if err := checkUniverse(syncAddr); err != nil {
+			return err
+		}
+		c.SyncAddr = syncAddr
+		return nil
+	}
+}
+
+// WithForceSync sets Config.ForceSync.
+func WithForceSync() Option {
+	return func(c *Config) error {
+		c.ForceSync = true
+		return nil
+	}
+}
+
+// WithoutSync makes data packets unsynchronized by default. This is the
+// default.
+func WithoutSync() Option {
+	return func(c *Config) error {
+		c.SyncAddr = NoSync
+		return nil
+	}
+}
+
+// WithIPv6 sends over IPv6 instead of IPv4.
+func WithIPv6() Option {
+	return func(c *Config) error {
+		c.IPv6 = true
+		return nil
+	}
+}
+
+// WithInterface sends multicast from ifi.
+func WithInterface(ifi *net.Interface) Option {
+	return func(c *Config) error {
+		c.Interface = ifi
+		return nil
+	}
+}
+
+// WithInterfaceName sends multicast from the interface called name.
+func WithInterfaceName(name string) Option {
+	return func(c *Config) error {
+		ifi, err := net.InterfaceByName(name)
+		if err != nil {
+			return err
+		}
+		c.Interface = ifi
+		return nil
+	}
+}
+
+// WithoutDiscovery stops the Sender advertising its universes.
+func WithoutDiscovery() Option {
+	return func(c *Config) error {
+		c.Discovery = false
+		return nil
+	}
+}
+
+// WithAdvertiseInterval advertises the Sender's universes every interval
+// instead of every DiscoveryInterval; see Config.AdvertiseInterval.
+func WithAdvertiseInterval(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval < MinAdvertiseInterval {
+			return errorf(ErrInvalidConfig, "Unable to set AdvertiseInterval (out of bounds)")
+		}
+		c.AdvertiseInterval = interval
+		return nil
+	}
+}
+
+// WithAdvertiseChanges advertises the Sender's universes as soon as they
+// change; see Config.AdvertiseChanges.
+func WithAdvertiseChanges() Option {
+	return func(c *Config) error {
+		c.AdvertiseChanges = true
+		return nil
+	}
+}
+
+// WithUnicast sends universe to addrs instead of its multicast group. It may
+// be given several times to configure several universes.
+func WithUnicast(universe uint16, addrs ...*net.UDPAddr) Option {
+	return func(c *Config) error {
+		if err := checkUnicast(universe, addrs); err != nil {
+			return err
+		}
+		unicast := make(map[uint16][]*net.UDPAddr, len(c.Unicast)+1)
+		for u, a := range c.Unicast {
+			unicast[u] = a
+		}
+		unicast[universe] = append([]*net.UDPAddr(nil), addrs...)
+		c.Unicast = unicast
+		return nil
+	}
+}
+
+// WithKeepAlive retransmits each universe's last packet after interval
+// without a send; see Config.KeepAlive.
+func WithKeepAlive(interval time.Duration) Option {
+	return func(c *Config) error {
+		if err := checkKeepAlive(interval); err != nil {
+			return err
+		}
+		c.KeepAlive = interval
+		return nil
+	}
+}
+
+// WithUniverseKeepAlive keeps universe alive every interval instead of
+// Config.KeepAlive, or not at all if interval is 0. It may be given several
+// times to configure several universes.
+func WithUniverseKeepAlive(universe uint16, interval time.Duration) Option {
+	return func(c *Config) error {
+		if err := checkUniverse(universe); err != nil {
+			return err
+		}
+		if err := checkKeepAlive(interval); err != nil {
+			return err
+		}
+		intervals := make(map[uint16]time.Duration, len(c.UniverseKeepAlive)+1)
+		for u, d := range c.UniverseKeepAlive {
+			intervals[u] = d
+		}
+		intervals[universe] = interval
+		c.UniverseKeepAlive = intervals
+		return nil
+	}
+}
+
+// WithDestinationKeepAlive keeps the universes unicast to addr alive at
+// least every interval; see Config.DestinationKeepAlive.
+func WithDestinationKeepAlive(addr *net.UDPAddr, interval time.Duration) Option {
+	return func(c *Config) error {
+		if addr == nil {
+			return errorf(ErrInvalidConfig, "Cannot set keep-alive for nil destination")
+		}
+		if err := checkKeepAlive(interval); err != nil {
+			return err
+		}
+		intervals := make(map[string]time.Duration, len(c.DestinationKeepAlive)+1)
+		for a, d := range c.DestinationKeepAlive {
+			intervals[a] = d
+		}
+		intervals[addr.String()] = interval
+		c.DestinationKeepAlive = intervals
+		return nil
+	}
+}
+
+// WithWithhold withholds each universe until it is marked initialized or
+// timeout passes; see Config.Withhold.
+func WithWithhold(timeout time.Duration) Option {
+	return func(c *Config) error {
+		if timeout < 0 {
+			return errorf(ErrInvalidConfig, "Unable to set Withhold (out of bounds)")
+		}
+		c.Withhold = timeout
+		return nil
+	}
+}
+
+// WithLimits caps the traffic the Sender will transmit.
+func WithLimits(l Limits) Option {
+	return func(c *Config) error {
+		if err := l.check(); err != nil {
+			return err
+		}
+		c.Limits = l
+		return nil
+	}
+}
+
+// WithLabels attaches channel metadata to the Config.
+func WithLabels(labels Labels) Option {
+	return func(c *Config) error {
+		c.Labels = labels
+		return nil
+	}
+}
+
+// New returns a Sender configured by applying opts to DefaultConfig.
+func New(opts ...Option) (*Sender, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return NewSender(cfg)
+}
+
+func checkSourceName(s string) error {
+	if len(s) == 0 {
+		return errorf(ErrSourceNameEmpty, "Cannot set empty e131 Source Name")
+	}
+	if len(s) > 63 {
+		return errorf(ErrSourceNameTooLong, "Cannot set e131 Source Name longer than 63 bytes")
+	}
+	return nil
+}
+
+// checkSyncAddr returns an error unless syncAddr is NoSync or a valid
+// universe.
+func checkSyncAddr(syncAddr uint16) error {
+	if syncAddr == NoSync {
+		return nil
+	}
+	return checkUniverse(syncAddr)
+}
+
+// checkUnicast returns an error unless universe is valid and addrs is a
+// non-empty list of addresses.
+func checkUnicast(universe uint16, addrs []*net.UDPAddr) error {
+	if err := checkUniverse(universe); err != nil {
+		return err
+	}
+	if len(addrs) == 0 {
+		return errorf(ErrInvalidConfig, "Cannot set empty unicast destination list for universe %d", universe)
+	}
+	for _, a := range addrs {
+		if a == nil {
+			return errorf(ErrInvalidConfig, "Cannot set nil unicast destination for universe %d", universe)
+		}
+	}
+	return nil
+}
+
+// checkKeepAlive returns an error unless interval is 0, which turns
+// keep-alive off, or at least MinKeepAlive.
+func checkKeepAlive(interval time.Duration) error {
+	if interval < 0 || interval > 0 && interval < MinKeepAlive {
+		return errorf(ErrInvalidConfig, "Unable to set KeepAlive (out of bounds)")
+	}
+	return nil
+}
+
+func checkPriority(i int) error {
+	if i < 0 || i > 200 {
+		return errorf(ErrPriorityOutOfRange, "Unable to set Priority (out of bounds)")
+	}
+	return nil
+}
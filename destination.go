@@ -0,0 +1,58 @@
+package e131
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// DestinationStatus is the send health of one destination of a universe.
+// Every packet of a universe is written to each of its destinations, which
+// share the universe's sequence numbers; a destination that fails is simply
+// tried again with the next packet, so one dead receiver does not hold up
+// the others.
+type DestinationStatus struct {
+	Addr net.Addr
+	// Sent and Failed count the packets written to Addr and the writes that
+	// failed.
+	Sent   uint64
+	Failed uint64
+	// ConsecutiveFailures counts the failures since the last successful
+	// write. It is zero while the destination is healthy.
+	ConsecutiveFailures int
+	// LastError is the most recent failure, at LastErrorAt.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// DestinationError is a failure to write a packet to one destination.
+type DestinationError struct {
+	Addr net.Addr
+	Err  error
+}
+
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("Cannot send to %v: %v", e.Addr, e.Err)
+}
+
+func (e *DestinationError) Unwrap() error { return e.Err }
+
+// failed records that a write failed with err at now.
+func (d *DestinationStatus) failed(err error, now time.Time) {
+	d.Failed++
+	d.ConsecutiveFailures++
+	d.LastError, d.LastErrorAt = err, now
+}
+
+// Destinations returns the health of each destination universe has been sent
+// to: its unicast addresses, or its multicast group. It is empty until the
+// universe is first sent.
+func (s *Sender) Destinations(universe uint16) []DestinationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var status []DestinationStatus
+	for _, d := range s.dests[universe] {
+		status = append(status, *d)
+	}
+	return status
+}
@@ -0,0 +1,62 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestUnicastDestinations(t *testing.T) {
+	a := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: Port}
+	b := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: Port}
+	c := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 3), Port: Port}
+	s, conn := testSender(t, Config{Unicast: map[uint16][]*net.UDPAddr{1: {a, b, c}}})
+	if st := s.Destinations(1); len(st) != 0 {
+		t.Fatalf("%d destinations before sending", len(st))
+	}
+	dead := errors.New("host unreachable")
+	conn.fail = map[string]error{b.String(): dead}
+
+	for i := 0; i < 3; i++ {
+		err := s.Send(0, Universe{Number: 1})
+		var derr *DestinationError
+		if !errors.As(err, &derr) || derr.Addr != b || !errors.Is(err, dead) {
+			t.Fatalf("send %d: got %v, want a DestinationError for %v", i, err, b)
+		}
+	}
+	seqs := make(map[string][]uint8)
+	for _, p := range conn.packets() {
+		f, err := ParseDataPacket(p.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs[p.addr.String()] = append(seqs[p.addr.String()], f.Sequence)
+	}
+	for _, addr := range []*net.UDPAddr{a, c} {
+		if got := seqs[addr.String()]; len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 2 {
+			t.Errorf("%v got sequence numbers %v, want 0 1 2", addr, got)
+		}
+	}
+
+	st := s.Destinations(1)
+	if len(st) != 3 {
+		t.Fatalf("%d destinations, want 3", len(st))
+	}
+	if st[0].Sent != 3 || st[0].Failed != 0 || st[2].Sent != 3 {
+		t.Errorf("healthy destinations: %+v, %+v", st[0], st[2])
+	}
+	if st[1].Sent != 0 || st[1].Failed != 3 || st[1].ConsecutiveFailures != 3 || st[1].LastError != dead || st[1].LastErrorAt.IsZero() {
+		t.Errorf("dead destination: %+v", st[1])
+	}
+
+	// The dead destination recovers with the next packet.
+	conn.mu.Lock()
+	conn.fail = nil
+	conn.mu.Unlock()
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Destinations(1)[1]; st.Sent != 1 || st.Failed != 3 || st.ConsecutiveFailures != 0 {
+		t.Errorf("recovered destination: %+v", st)
+	}
+}
@@ -0,0 +1,99 @@
+package e131
+
+import (
+	"sort"
+	"time"
+)
+
+// DiscoveryInterval is E131_UNIVERSE_DISCOVERY_INTERVAL, how often a source
+// advertises the universes it is sending.
+const DiscoveryInterval = 10 * time.Second
+
+// maxDiscoveryUniverses is the number of universes one discovery page can
+// list.
+const maxDiscoveryUniverses = 512
+
+// advertise sends a universe discovery packet every advertising interval,
+// and on the changes signalled on s.changed, until the Sender is closed.
+func (s *Sender) advertise() {
+	interval := s.cfg.AdvertiseInterval
+	if interval == 0 {
+		interval = DiscoveryInterval
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-tick.C:
+			s.mu.Lock()
+			s.sendDiscoveryLocked(now)
+			s.mu.Unlock()
+		case <-s.changed:
+			s.mu.Lock()
+			if !equalUniverses(s.activeUniverses(), s.advertised) {
+				s.sendDiscoveryLocked(time.Now())
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// streamsChangedLocked tells advertise, with s.mu held, that streams may
+// have started or stopped, if the Sender advertises changes.
+func (s *Sender) streamsChangedLocked() {
+	if !s.cfg.AdvertiseChanges {
+		return
+	}
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// activeUniverses returns the universes the Sender is sending, in ascending
+// order. A universe stops being active when it is terminated.
+func (s *Sender) activeUniverses() []uint16 {
+	seen := make(map[uint16]bool)
+	var universes []uint16
+	for key := range s.last {
+		if !seen[key.universe] {
+			seen[key.universe] = true
+			universes = append(universes, key.universe)
+		}
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i] < universes[j] })
+	return universes
+}
+
+// sendDiscoveryLocked advertises the active universes, with s.mu held, in as
+// many pages as needed. It sends nothing while no universe is active.
+func (s *Sender) sendDiscoveryLocked(now time.Time) error {
+	if s.conn == nil {
+		return nil
+	}
+	active := s.activeUniverses()
+	s.advertised = active
+	if len(active) == 0 {
+		return nil
+	}
+	universes := make([]Universe, len(active))
+	for i, n := range active {
+		universes[i].Number = n
+	}
+	packets, err := discPackets(&s.cfg, universes)
+	if err != nil {
+		return err
+	}
+	dests := s.destinations(DiscoveryUniverse)
+	for _, data := range packets {
+		if err := s.rate.allow(s.cfg.Limits, now, len(dests), len(data)); err != nil {
+			return err
+		}
+		if err := s.write(data, dests, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package e131
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvertiseIntervalConfig(t *testing.T) {
+	for _, d := range []time.Duration{-time.Second, time.Millisecond} {
+		if err := (Config{SourceName: "test", AdvertiseInterval: d}).check(); err == nil {
+			t.Errorf("AdvertiseInterval %v accepted", d)
+		}
+	}
+	if _, err := New(WithAdvertiseInterval(0)); err == nil {
+		t.Error("WithAdvertiseInterval(0) accepted")
+	}
+}
+
+func TestAdvertiseChanges(t *testing.T) {
+	c := newMemConn()
+	s, err := NewSenderConn(Config{SourceName: "test", Discovery: true, AdvertiseChanges: true}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// advertised waits for the next discovery packet after the first n
+	// packets written and returns its universes.
+	advertised := func(n int) []uint16 {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			for _, p := range c.packets()[n:] {
+				if Classify(p.data) != PacketDiscovery {
+					continue
+				}
+				f, err := ParseDiscoveryPacket(p.data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return f.Universes
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("no discovery packet")
+		return nil
+	}
+	for _, step := range []struct {
+		do   func() error
+		want []uint16
+	}{
+		{func() error { return s.Send(0, Universe{Number: 1}) }, []uint16{1}},
+		{func() error { return s.Send(0, Universe{Number: 2}) }, []uint16{1, 2}},
+		{func() error { return s.Terminate(1) }, []uint16{2}},
+	} {
+		n := len(c.packets())
+		if err := step.do(); err != nil {
+			t.Fatal(err)
+		}
+		if got := advertised(n); !equalUniverses(got, step.want) {
+			t.Errorf("advertised %v, want %v", got, step.want)
+		}
+	}
+}
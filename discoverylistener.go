@@ -0,0 +1,289 @@
+package e131
+
+import (
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// discoveryTimeout is how long a DiscoveryListener keeps a source that has
+// stopped advertising: two missed discovery intervals.
+const discoveryTimeout = 2 * DiscoveryInterval
+
+// DiscoveredSource is a source found through universe discovery.
+type DiscoveredSource struct {
+	CID        uuid.UUID
+	SourceName string
+	// IP is the address the source's discovery packets came from, or nil
+	// if the transport does not report one.
+	IP net.IP
+	// Universes are the universes the source advertises, in ascending
+	// order.
+	Universes []uint16
+	LastSeen  time.Time
+}
+
+// DiscoveryEvent reports a change in the sources a DiscoveryListener knows.
+type DiscoveryEvent struct {
+	Source DiscoveredSource
+	// Removed is true if the source stopped advertising, and false if it
+	// appeared or changed its name, address or universes.
+	Removed bool
+}
+
+// DiscoveryListener keeps a live table of the sources on the network and the
+// universes they offer, from the universe discovery packets they send.
+type DiscoveryListener struct {
+	conn PacketConn
+
+	mu       sync.Mutex
+	sources  map[uuid.UUID]*DiscoveredSource
+	pages    map[uuid.UUID]*discoveryPages
+	onChange func(DiscoveryEvent)
+	bus      *EventBus
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDiscoveryListener joins the universe discovery multicast group and
+// starts listening. Close must be called to release its socket.
+func NewDiscoveryListener() (*DiscoveryListener, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, DiscoveryAddr())
+	if err != nil {
+		return nil, err
+	}
+	return NewDiscoveryListenerConn(conn), nil
+}
+
+// NewDiscoveryListener6 is like NewDiscoveryListener but joins the IPv6
+// universe discovery group, for sources sending with Config.IPv6.
+func NewDiscoveryListener6() (*DiscoveryListener, error) {
+	conn, err := net.ListenMulticastUDP("udp6", nil, MulticastAddr6(DiscoveryUniverse))
+	if err != nil {
+		return nil, err
+	}
+	return NewDiscoveryListenerConn(conn), nil
+}
+
+// NewDiscoveryListenerConn returns a DiscoveryListener that reads discovery
+// packets from conn. Close closes conn.
+func NewDiscoveryListenerConn(conn PacketConn) *DiscoveryListener {
+	l := &DiscoveryListener{
+		conn:    conn,
+		sources: make(map[uuid.UUID]*DiscoveredSource),
+		pages:   make(map[uuid.UUID]*discoveryPages),
+		done:    make(chan struct{}),
+	}
+	l.wg.Add(2)
+	go l.listen()
+	go l.watchTimeouts(l.done)
+	return l
+}
+
+// OnChange sets fn to be called whenever a source appears, changes or is
+// removed. fn is called from the listener's goroutines.
+//
+// Deprecated: Subscribe to EventDiscovery on an EventBus given to
+// SetEventBus, which carries every kind of notification.
+func (l *DiscoveryListener) OnChange(fn func(DiscoveryEvent)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onChange = fn
+}
+
+// SetEventBus publishes a DiscoveryEvent on bus whenever a source appears,
+// changes or is removed. nil stops publishing.
+func (l *DiscoveryListener) SetEventBus(bus *EventBus) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bus = bus
+}
+
+// Sources returns the sources currently advertising, ordered by name.
+func (l *DiscoveryListener) Sources() []DiscoveredSource {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sources := make([]DiscoveredSource, 0, len(l.sources))
+	for _, s := range l.sources {
+		sources = append(sources, *s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].SourceName < sources[j].SourceName
+	})
+	return sources
+}
+
+// Close stops listening and closes the transport.
+func (l *DiscoveryListener) Close() error {
+	l.mu.Lock()
+	if l.done == nil {
+		l.mu.Unlock()
+		return nil
+	}
+	close(l.done)
+	l.done = nil
+	l.mu.Unlock()
+	err := l.conn.Close()
+	l.wg.Wait()
+	return err
+}
+
+// listen reads discovery packets until the transport is closed.
+func (l *DiscoveryListener) listen() {
+	defer l.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := l.conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		f, err := ParseDiscoveryPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		var ip net.IP
+		if udp, ok := addr.(*net.UDPAddr); ok {
+			ip = udp.IP
+		}
+		l.update(f, ip, time.Now())
+	}
+}
+
+// discoveryPages collects the pages of one source's universe list.
+type discoveryPages struct {
+	lastPage uint8
+	pages    map[uint8][]uint16
+	// seen is when a page last arrived, for expiring sets that never
+	// complete.
+	seen time.Time
+}
+
+// add records page f, received at now, and returns the complete universe
+// list, or false if pages are still missing. Sources send their pages in
+// order every DiscoveryInterval, so page 0 starts a new set, and a set is
+// cleared once complete so that no page counts towards two.
+func (p *discoveryPages) add(f DiscoveryFrame, now time.Time) ([]uint16, bool) {
+	if p.pages == nil || f.Page == 0 || p.lastPage != f.LastPage {
+		p.lastPage = f.LastPage
+		p.pages = make(map[uint8][]uint16)
+	}
+	p.seen = now
+	p.pages[f.Page] = f.Universes
+	if len(p.pages) != int(p.lastPage)+1 {
+		return nil, false
+	}
+	var universes []uint16
+	for i := 0; i <= int(p.lastPage); i++ {
+		universes = append(universes, p.pages[uint8(i)]...)
+	}
+	p.pages = nil
+	return universes, true
+}
+
+// update records the discovery packet f, received from ip at now. A source's
+// universes are only updated once every page of its list has arrived.
+func (l *DiscoveryListener) update(f DiscoveryFrame, ip net.IP, now time.Time) {
+	if f.Page > f.LastPage {
+		return
+	}
+
+	l.mu.Lock()
+	p := l.pages[f.CID]
+	if p == nil {
+		p = &discoveryPages{}
+		l.pages[f.CID] = p
+	}
+	universes, complete := p.add(f, now)
+	s := l.sources[f.CID]
+	if !complete {
+		if s != nil {
+			s.LastSeen = now
+		}
+		l.mu.Unlock()
+		return
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i] < universes[j] })
+	changed := s == nil || s.SourceName != f.SourceName || !s.IP.Equal(ip) || !equalUniverses(s.Universes, universes)
+	if s == nil {
+		s = &DiscoveredSource{CID: f.CID}
+		l.sources[f.CID] = s
+	}
+	s.SourceName, s.IP, s.Universes, s.LastSeen = f.SourceName, ip, universes, now
+	event := DiscoveryEvent{Source: *s}
+	fn, bus := l.onChange, l.bus
+	l.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if fn != nil {
+		fn(event)
+	}
+	bus.publish(event)
+}
+
+// watchTimeouts removes sources that stop advertising until the listener is
+// closed.
+func (l *DiscoveryListener) watchTimeouts(done <-chan struct{}) {
+	defer l.wg.Done()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-tick.C:
+			l.expire(now)
+		}
+	}
+}
+
+// expire removes sources not seen for discoveryTimeout before now, and the
+// pages of incomplete lists that have stopped arriving.
+func (l *DiscoveryListener) expire(now time.Time) {
+	var removed []DiscoveryEvent
+	l.mu.Lock()
+	for cid, p := range l.pages {
+		if now.Sub(p.seen) > discoveryTimeout {
+			delete(l.pages, cid)
+		}
+	}
+	for cid, s := range l.sources {
+		if now.Sub(s.LastSeen) > discoveryTimeout {
+			delete(l.sources, cid)
+			delete(l.pages, cid)
+			removed = append(removed, DiscoveryEvent{Source: *s, Removed: true})
+		}
+	}
+	fn, bus := l.onChange, l.bus
+	l.mu.Unlock()
+
+	for _, e := range removed {
+		if fn != nil {
+			fn(e)
+		}
+		bus.publish(e)
+	}
+}
+
+// equalUniverses reports whether a and b list the same universes in the same
+// order.
+func equalUniverses(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
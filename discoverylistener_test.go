@@ -0,0 +1,97 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"testing"
+	"time"
+)
+
+// discoveryCycle returns the pages a source advertising universes sends in
+// one discovery interval.
+func discoveryCycle(t *testing.T, cid uuid.UUID, universes []uint16) []DiscoveryFrame {
+	t.Helper()
+	us := make([]Universe, len(universes))
+	for i, n := range universes {
+		us[i].Number = n
+	}
+	packets, err := discPackets(&Config{CID: cid, SourceName: "test"}, us)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := make([]DiscoveryFrame, len(packets))
+	for i, b := range packets {
+		if frames[i], err = ParseDiscoveryPacket(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return frames
+}
+
+// universeRange returns the universes from first to last.
+func universeRange(first, last uint16) []uint16 {
+	var s []uint16
+	for u := first; u <= last; u++ {
+		s = append(s, u)
+	}
+	return s
+}
+
+func TestDiscoveryPaging(t *testing.T) {
+	l := NewDiscoveryListenerConn(newMemConn())
+	defer l.Close()
+	var events []DiscoveryEvent
+	l.OnChange(func(e DiscoveryEvent) { events = append(events, e) })
+
+	cid := uuid.NewV4()
+	now := time.Now()
+	for i, universes := range [][]uint16{
+		universeRange(1, 600),
+		append(universeRange(2, 513), universeRange(1000, 1087)...),
+		// An unchanged list sends no event.
+		append(universeRange(2, 513), universeRange(1000, 1087)...),
+	} {
+		before := len(events)
+		pages := discoveryCycle(t, cid, universes)
+		if len(pages) != 2 {
+			t.Fatalf("cycle %d has %d pages, want 2", i, len(pages))
+		}
+		for _, f := range pages {
+			if len(events) != before {
+				t.Fatalf("cycle %d: change reported before its last page", i)
+			}
+			l.update(f, nil, now)
+		}
+		now = now.Add(DiscoveryInterval)
+
+		want := 1
+		if i == 2 {
+			want = 0
+		}
+		if got := len(events) - before; got != want {
+			t.Fatalf("cycle %d: %d change events, want %d", i, got, want)
+		}
+		if want == 1 && !equalUniverses(events[before].Source.Universes, universes) {
+			t.Errorf("cycle %d: reported %d universes, not the advertised %d", i, len(events[before].Source.Universes), len(universes))
+		}
+	}
+}
+
+func TestDiscoveryExpiresIncompletePages(t *testing.T) {
+	l := NewDiscoveryListenerConn(newMemConn())
+	defer l.Close()
+	now := time.Now()
+	pages := discoveryCycle(t, uuid.NewV4(), universeRange(1, 600))
+	l.update(pages[0], nil, now)
+
+	l.expire(now.Add(discoveryTimeout))
+	if len(l.pages) != 1 {
+		t.Fatal("pages expired early")
+	}
+	l.expire(now.Add(discoveryTimeout + time.Second))
+	if len(l.pages) != 0 {
+		t.Error("incomplete pages never expire")
+	}
+	if len(l.Sources()) != 0 {
+		t.Error("incomplete list added a source")
+	}
+}
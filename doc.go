@@ -0,0 +1,24 @@
+// Package e131 implements the ANSI E1.31 (Streaming ACN, or sACN) protocol
+// for sending and receiving DMX512 data over IP networks.
+//
+// A Sender transmits universes as one source, described by a Config:
+//
+//	s, err := e131.New(e131.WithSourceName("console"), e131.WithPriority(100))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer s.Close()
+//	u := e131.Universe{Number: 1}
+//	u.Slots[0] = 255
+//	err = s.Send(0, u)
+//
+// A Receiver joins universes and hands each decoded DataFrame to a handler.
+// A Merger combines frames from competing sources into one output per
+// universe, following the E1.31 priority rules, and a StartCodeMux routes
+// frames with alternate START codes.
+//
+// ParseDataPacket, ParseSyncPacket and ParseDiscoveryPacket decode raw
+// packets, and Annotate prints them field by field for debugging.
+//
+// Sub-packages under cmd provide command-line tools built on the package.
+package e131
@@ -1,25 +1,156 @@
 package e131
 
 import (
+	"bytes"
 	"encoding/binary"
-	"fmt"
 	uuid "github.com/satori/go.uuid"
-	"os"
+	"net"
 )
 
 type Universe struct {
 	Slots  [512]byte
-	Number uint8
+	Number uint16
+	// Priorities optionally holds a per-address priority (1-200) for each
+	// slot, as carried by packets with the 0xDD start code. A value of 0
+	// means the source is not driving that slot. It is nil when the source
+	// only sends universe-level priority.
+	Priorities *[512]byte
 }
 
+// START codes for the payload carried by a data packet.
+const (
+	// NullStartCode marks slots carrying DMX levels.
+	NullStartCode = 0x00
+	// PriorityStartCode marks slots carrying per-address priorities.
+	PriorityStartCode = 0xdd
+)
+
+// Valid data universe numbers. Other values are reserved by E1.31.
+const (
+	MinUniverse = 1
+	MaxUniverse = 63999
+)
+
+// checkUniverse returns an error if n is not a valid data universe number.
+func checkUniverse(n uint16) error {
+	if n < MinUniverse || n > MaxUniverse {
+		return errorf(ErrUniverseOutOfRange, "Universe %d out of range (%d-%d)", n, MinUniverse, MaxUniverse)
+	}
+	return nil
+}
+
+// StartCode returns a pointer to Slots[0] of a copy of u.
+//
+// Deprecated: Slots[0] is DMX address 1, not the START code, which packets
+// carry separately (NullStartCode, DataFrame.StartCode). The pointer refers
+// to a copy of u, so writes through it are lost.
 func (u Universe) StartCode() *byte {
 	return &u.Slots[0]
 }
 
+// Data returns Slots[1:] of a copy of u.
+//
+// Deprecated: Data omits DMX address 1, which is Slots[0]. Use Slots.
 func (u Universe) Data() []byte {
 	return u.Slots[1:]
 }
 
+// Normalized returns the slot values scaled to float32 in the range 0.0-1.0.
+// dst is reused when it has enough capacity so callers converting every frame
+// need not allocate.
+func (u Universe) Normalized(dst []float32) []float32 {
+	if cap(dst) < len(u.Slots) {
+		dst = make([]float32, len(u.Slots))
+	}
+	dst = dst[:len(u.Slots)]
+	for i, v := range u.Slots {
+		dst[i] = float32(v) / 255
+	}
+	return dst
+}
+
+// ChannelChange is a write of Value to the slot at index Channel of a
+// Universe.
+type ChannelChange struct {
+	Channel int
+	Value   byte
+}
+
+// Apply writes each change to u's slots in order. It validates every channel
+// first, so on error u is left unchanged.
+func (u *Universe) Apply(changes []ChannelChange) error {
+	for _, c := range changes {
+		if c.Channel < 0 || c.Channel >= len(u.Slots) {
+			return errorf(ErrChannelOutOfRange, "Channel %d out of range (0-%d)", c.Channel, len(u.Slots)-1)
+		}
+	}
+	for _, c := range changes {
+		u.Slots[c.Channel] = c.Value
+	}
+	return nil
+}
+
+// Diff returns the minimal set of channel writes that turns from into to, in
+// ascending channel order. It returns nil when the slots are identical.
+func Diff(from, to Universe) []ChannelChange {
+	var changes []ChannelChange
+	for i := range to.Slots {
+		if from.Slots[i] != to.Slots[i] {
+			changes = append(changes, ChannelChange{Channel: i, Value: to.Slots[i]})
+		}
+	}
+	return changes
+}
+
+// Port is the UDP port on which all sACN traffic is sent and received.
+const Port = 5568
+
+// DiscoveryUniverse is the reserved universe on which universe discovery
+// packets are sent. It lies outside the data universe range.
+const DiscoveryUniverse = 64214
+
+// DiscoveryAddr returns the multicast group and port that carry universe
+// discovery packets, 239.255.250.214:5568.
+func DiscoveryAddr() *net.UDPAddr {
+	return MulticastAddr(DiscoveryUniverse)
+}
+
+// MulticastAddr returns the IPv4 multicast group and port that carry
+// universe: 239.255.{high byte}.{low byte}:5568. It is defined for any
+// universe number, including DiscoveryUniverse.
+func MulticastAddr(universe uint16) *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   net.IPv4(239, 255, byte(universe>>8), byte(universe)),
+		Port: Port,
+	}
+}
+
+// MulticastAddr6 returns the IPv6 multicast group and port that carry
+// universe, port 5568. E1.31 writes the group bytewise as
+// FF18::83:00:{high byte}:{low byte}: its last four bytes are 0x83, 0x00 and
+// the universe number, so universe 1 is ff18::8300:1.
+func MulticastAddr6(universe uint16) *net.UDPAddr {
+	ip := net.IP{0xff, 0x18, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x83, 0, byte(universe >> 8), byte(universe)}
+	return &net.UDPAddr{IP: ip, Port: Port}
+}
+
+// multicastAddr returns the IPv6 group for universe if ipv6 is set, or the
+// IPv4 group otherwise.
+func multicastAddr(universe uint16, ipv6 bool) *net.UDPAddr {
+	if ipv6 {
+		return MulticastAddr6(universe)
+	}
+	return MulticastAddr(universe)
+}
+
+// network returns the net package network name for ipv6.
+func network(ipv6 bool) string {
+	if ipv6 {
+		return "udp6"
+	}
+	return "udp4"
+}
+
 // e1.31 Root Layer Packet (rlp) constants
 var (
 	rlpPreambleSize                  = []byte{0x00, 0x10}
@@ -30,12 +161,6 @@ var (
 	rlpVectorRootE131Extended        = []byte{0x00, 0x00, 0x00, 0x08}
 )
 
-// e1.31 rlp vars
-
-// rlpCid is the UUID that corresponds to a network component. For hardware this
-// is in ROM. For software, it should be generated.
-var rlpCid uuid.UUID
-
 // e1.31 Framing Layer Packet (flp) constants
 var (
 	flpProtoFlags             uint16 = 0x7000
@@ -47,43 +172,63 @@ var (
 	flpForceSyncFlag                 = []byte{0x20}
 )
 
-// e1.31 flp vars
-
-// flpSourceName is a user-assigned name. It's default value will be
-// go131-[PID]
-var flpSourceName []byte
+// defaultConfig is the source identity used by the package-level functions
+// below and DataPacket. It is not safe for concurrent use; programs with more
+// than one source, or that configure sources from several goroutines, should
+// give each its own Sender and Config.
+var defaultConfig Config
 
 // SetSourceName sets the user-assigned source name for the framing layer of
 // the sACN packet.
+//
+// Deprecated: Use Config.SourceName, or WithSourceName with New.
 func SetSourceName(s string) error {
-	if len(s) == 0 {
-		return fmt.Errorf("Cannot set empty e131 Source Name")
-	}
-	if len(s) > 63 {
-		return fmt.Errorf("Cannot set e131 Source Name longer than 63 bytes")
+	if err := checkSourceName(s); err != nil {
+		return err
 	}
-	copy(flpSourceName[:], s)
+	defaultConfig.SourceName = s
 	return nil
 }
 
 // SourceName returns the user-assigned source name used by the framing layer
 // of the sACN packet.
+//
+// Deprecated: Use Config.SourceName.
 func SourceName() string {
-	return string(flpSourceName[:])
+	return defaultConfig.SourceName
 }
 
-// DMX Priority must be from 0-200, default 100
-var flpPriority uint8
+// appendSourceName appends s as the 64-byte, null-padded source name field.
+func appendSourceName(data []byte, s string) []byte {
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	data = append(data, s...)
+	for i := len(s); i < 64; i++ {
+		data = append(data, 0x00)
+	}
+	return data
+}
+
+// nullTerminated returns the string in b up to the first null byte.
+func nullTerminated(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
 
 // SetPriority sets the DMX message priority. It should be from 0-200 with 100
 // being the default. The priority 100 has greater priority than 0 and less
 // priority than 200.
+//
+// Deprecated: Use Config.Priority, or WithPriority with New.
 func SetPriority(i int) error {
-	if i >= 0 && i <= 200 {
-		flpPriority = uint8(i)
-		return nil
+	if err := checkPriority(i); err != nil {
+		return err
 	}
-	return fmt.Errorf("Unable to set Priority (out of bounds)")
+	defaultConfig.Priority = uint8(i)
+	return nil
 }
 
 // e1.31 DMP Layer Packet (dmp) constants
@@ -102,63 +247,94 @@ var (
 )
 
 func init() {
-	rlpCid = uuid.NewV4()
-	if err := SetSourceName(fmt.Sprintf("go131-%d", os.Getpid())); err != nil {
-		panic(err)
-	}
-	if err := SetPriority(100); err != nil {
-		panic(err)
-	}
+	defaultConfig = DefaultConfig()
 }
 
 // build the root layer
-func packetRootLayer(vector []byte, dataLength uint16) []byte {
-	var data []byte
+func packetRootLayer(cid uuid.UUID, vector []byte, dataLength uint16) []byte {
+	return appendRootLayer(nil, cid, vector, dataLength)
+}
 
+// appendRootLayer appends the root layer to data.
+func appendRootLayer(data []byte, cid uuid.UUID, vector []byte, dataLength uint16) []byte {
 	data = append(data, rlpPreambleSize...)
 	data = append(data, rlpPostambleSize...)
 	data = append(data, rlpAcnPacketIdentifier...)
-	data = append(data, byte(dataLength|rlpProtoFlags))
-	data = append(data, []byte(vector)...)
+	data = append(data, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], dataLength|rlpProtoFlags)
+	data = append(data, vector...)
+	data = append(data, cid.Bytes()...)
 	return data
 }
 
-func discPacket(syncAddr uint16, seqID uint8, universes []Universe) ([]byte, error) {
-	var universeIDs []uint8
+// discPackets builds the pages of universe discovery packets listing
+// universes, which must be in ascending order.
+func discPackets(cfg *Config, universes []Universe) ([][]byte, error) {
+	pages := (len(universes) + maxDiscoveryUniverses - 1) / maxDiscoveryUniverses
+	if pages == 0 {
+		pages = 1
+	}
+	if pages > 256 {
+		return nil, errorf(ErrTooManyUniverses, "Cannot advertise %d universes (out of bounds)", len(universes))
+	}
+	packets := make([][]byte, pages)
+	for i := range packets {
+		page := universes[i*maxDiscoveryUniverses:]
+		if len(page) > maxDiscoveryUniverses {
+			page = page[:maxDiscoveryUniverses]
+		}
+		var err error
+		if packets[i], err = discPacket(cfg, uint8(i), uint8(pages-1), page); err != nil {
+			return nil, err
+		}
+	}
+	return packets, nil
+}
+
+// discPacket builds one page of a universe discovery packet. A page lists at
+// most maxDiscoveryUniverses universes.
+func discPacket(cfg *Config, page, lastPage uint8, universes []Universe) ([]byte, error) {
+	if len(universes) > maxDiscoveryUniverses {
+		return nil, errorf(ErrTooManyUniverses, "Cannot list %d universes on one discovery page", len(universes))
+	}
+	var universeIDs []byte
 	for _, v := range universes {
-		universeIDs = append(universeIDs, v.Number)
+		if err := checkUniverse(v.Number); err != nil {
+			return nil, err
+		}
+		universeIDs = append(universeIDs, 0x00, 0x00)
+		binary.BigEndian.PutUint16(universeIDs[len(universeIDs)-2:], v.Number)
 	}
 
 	var data []byte
 	// build the root layer
-	data = append(data, packetRootLayer(rlpVectorRootE131Extended, uint16(len(universeIDs)*2+104))...)
+	data = append(data, packetRootLayer(cfg.CID, rlpVectorRootE131Extended, uint16(len(universeIDs)+104))...)
 
 	// build the framing layer
 	data = append(data, 0x00, 0x00)
-	flpLength := uint16((len(universeIDs)*2 + 82)) | flpProtoFlags
+	flpLength := uint16((len(universeIDs) + 82)) | flpProtoFlags
 	binary.BigEndian.PutUint16(data[len(data)-2:], flpLength)
 
 	data = append(data, flpVectorE131ExtendedDisc...)
-	data = append(data, flpSourceName...)
+	data = appendSourceName(data, cfg.SourceName)
 	data = append(data, 0x00, 0x00, 0x00, 0x00)
 
 	// build the universe discovery layer
 	data = append(data, 0x00, 0x00)
-	udlLength := uint16((len(universeIDs) + 8)) | flpProtoFlags
+	udlLength := uint16((len(universeIDs) + 8)) | udlProtoFlags
 	binary.BigEndian.PutUint16(data[len(data)-2:], udlLength)
 
-	// hard-coding page=0 lastpage=0 gives us a max of 512 universes
 	data = append(data, udlVectorUnivDiscUnivList...)
-	data = append(data, 0x00, 0x00)
+	data = append(data, page, lastPage)
 	data = append(data, universeIDs...)
 
 	return data, nil
 }
 
-func syncPacket(syncAddr uint8, seqID uint8) ([]byte, error) {
-	var data []byte
+// syncPacket appends a synchronization packet for syncAddr to data.
+func syncPacket(data []byte, cfg *Config, syncAddr uint16, seqID uint8) ([]byte, error) {
 	// build the root layer
-	data = packetRootLayer(rlpVectorRootE131Extended, 33)
+	data = appendRootLayer(data, cfg.CID, rlpVectorRootE131Extended, 33)
 
 	// build the framing layer
 	data = append(data, 0x00, 0x00)
@@ -167,46 +343,125 @@ func syncPacket(syncAddr uint8, seqID uint8) ([]byte, error) {
 
 	data = append(data, flpVectorE131ExtendedSync...)
 	data = append(data, seqID)
-	data = append(data, syncAddr)
+	data = append(data, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], syncAddr)
 	data = append(data, 0x00, 0x00) // reserved bytes
 	return data, nil
 }
 
-// return data packet payload or error
+// return data packet payload or error. syncAddr is NoSync for data that is not
+// synchronized.
+//
+// Deprecated: Use Config.DataPacket, or a Sender.
 func DataPacket(syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
-	var data []byte
+	return dataPacket(nil, &defaultConfig, syncAddr, seqID, optionsFlags, universe)
+}
+
+// PriorityPacket returns a data packet with the 0xDD start code carrying
+// universe.Priorities, which must not be nil.
+//
+// Deprecated: Use Config.PriorityPacket, or a Sender.
+func PriorityPacket(syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return priorityPacket(nil, &defaultConfig, syncAddr, seqID, optionsFlags, universe)
+}
+
+// DataPacket returns a data packet of universe's levels sent as the source c.
+// syncAddr is NoSync for data that is not synchronized.
+func (c Config) DataPacket(syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return dataPacket(nil, &c, syncAddr, seqID, optionsFlags, universe)
+}
+
+// AppendDataPacket is like DataPacket but appends the packet to dst and
+// returns the extended buffer. Reusing a buffer with capacity for a full
+// packet, 638 bytes, encodes without allocating. On error dst is returned
+// unchanged.
+func (c Config) AppendDataPacket(dst []byte, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return dataPacket(dst, &c, syncAddr, seqID, optionsFlags, universe)
+}
+
+// PriorityPacket returns a data packet with the 0xDD start code carrying
+// universe.Priorities, which must not be nil, sent as the source c.
+func (c Config) PriorityPacket(syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return priorityPacket(nil, &c, syncAddr, seqID, optionsFlags, universe)
+}
+
+// AppendPriorityPacket is like PriorityPacket but appends the packet to dst
+// and returns the extended buffer, or dst unchanged on error.
+func (c Config) AppendPriorityPacket(dst []byte, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return priorityPacket(dst, &c, syncAddr, seqID, optionsFlags, universe)
+}
+
+// StartCodePacket returns a data packet for universe carrying startCode
+// followed by payload, up to 512 bytes, sent as the source c. It carries
+// alternate START codes, such as 0xCC for RDM, opaquely and at their own
+// length; receivers find the payload with DataFrame.Payload.
+func (c Config) StartCodePacket(syncAddr uint16, seqID uint8, optionsFlags byte, startCode byte, universe uint16, payload []byte) ([]byte, error) {
+	return appendStartCodePacket(nil, &c, syncAddr, seqID, optionsFlags, startCode, universe, payload)
+}
+
+// SyncPacket returns a synchronization packet for syncAddr sent as the source
+// c.
+func (c Config) SyncPacket(syncAddr uint16, seqID uint8) ([]byte, error) {
+	return syncPacket(nil, &c, syncAddr, seqID)
+}
+
+// dataPacket builds a data packet of universe's levels sent by the source
+// cfg.
+func dataPacket(dst []byte, cfg *Config, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	return appendStartCodePacket(dst, cfg, syncAddr, seqID, optionsFlags, NullStartCode, universe.Number, universe.Slots[:])
+}
+
+// priorityPacket builds a data packet of universe's per-address priorities
+// sent by the source cfg.
+func priorityPacket(dst []byte, cfg *Config, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+	if universe.Priorities == nil {
+		return dst, errorf(ErrNoPriorities, "Cannot build priority packet: universe %d has no Priorities", universe.Number)
+	}
+	return appendStartCodePacket(dst, cfg, syncAddr, seqID, optionsFlags, PriorityStartCode, universe.Number, universe.Priorities[:])
+}
+
+// appendStartCodePacket appends a data packet carrying startCode followed by
+// slots, at most 512 of them, to data. On error it returns data unchanged.
+func appendStartCodePacket(data []byte, cfg *Config, syncAddr uint16, seqID uint8, optionsFlags byte, startCode byte, number uint16, slots []byte) ([]byte, error) {
+	if err := checkUniverse(number); err != nil {
+		return data, err
+	}
+	if len(slots) > 512 {
+		return data, errorf(ErrInvalidArgument, "Cannot send %d slots after the START code (at most 512)", len(slots))
+	}
+
 	// build the root layer
-	data = packetRootLayer(rlpVectorRootE131Data, uint16(len(universe.Slots)+109))
+	data = appendRootLayer(data, cfg.CID, rlpVectorRootE131Data, uint16(len(slots)+110))
 
 	// build the framing layer
 	data = append(data, 0x00, 0x00)
-	flpLength := uint16((len(universe.Slots) + 87)) | flpProtoFlags
+	flpLength := uint16((len(slots) + 88)) | flpProtoFlags
 	binary.BigEndian.PutUint16(data[len(data)-2:], flpLength)
 
 	data = append(data, flpVectorE131DataPacket...)
-	data = append(data, flpSourceName...)
-	data = append(data, flpPriority)
-	addrBytes := make([]byte, 2)
-	binary.BigEndian.PutUint16(addrBytes, syncAddr)
-	data = append(data, addrBytes...)
+	data = appendSourceName(data, cfg.SourceName)
+	data = append(data, cfg.Priority)
+	data = append(data, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], syncAddr)
 	data = append(data, seqID)
 	data = append(data, optionsFlags)
-	data = append(data, universe.Number)
+	data = append(data, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], number)
 
 	// build the dmp layer
 	data = append(data, 0x00, 0x00)
-	dmpLength := uint16((len(universe.Slots) + 10)) | dmpProtoFlags
+	dmpLength := uint16((len(slots) + 11)) | dmpProtoFlags
 	binary.BigEndian.PutUint16(data[len(data)-2:], dmpLength)
 
 	data = append(data, dmpVectorDmpSetProperty...)
 	data = append(data, dmpAddressTypeDataType...)
 	data = append(data, dmpFirstPropertyAddress...)
 	data = append(data, dmpAddressIncrement...)
-	// we hard-code 513 as the Property Value Count since we send the entire
-	// 512 byte universe and the start code, then we encode a 0-value start
-	// code
-	data = append(data, 0x02, 0x01, 0x00)
-	data = append(data, universe.Slots[:]...)
+	// the Property Value Count is the slots and the start code
+	data = append(data, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(slots)+1))
+	data = append(data, startCode)
+	data = append(data, slots...)
 
 	return data, nil
 }
@@ -0,0 +1,74 @@
+package e131
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMulticastAddr(t *testing.T) {
+	for _, tc := range []struct {
+		universe uint16
+		ipv4     string
+		ipv6     string
+	}{
+		{1, "239.255.0.1", "ff18::8300:1"},
+		{256, "239.255.1.0", "ff18::8300:100"},
+		{63999, "239.255.249.255", "ff18::8300:f9ff"},
+		{DiscoveryUniverse, "239.255.250.214", "ff18::8300:fad6"},
+	} {
+		if got := MulticastAddr(tc.universe); got.IP.String() != tc.ipv4 || got.Port != Port {
+			t.Errorf("MulticastAddr(%d) = %v, want %s:%d", tc.universe, got, tc.ipv4, Port)
+		}
+		if got := MulticastAddr6(tc.universe); got.IP.String() != tc.ipv6 || got.Port != Port {
+			t.Errorf("MulticastAddr6(%d) = %v, want [%s]:%d", tc.universe, got, tc.ipv6, Port)
+		}
+	}
+}
+
+func TestAppendPacketError(t *testing.T) {
+	cfg := Config{SourceName: "test"}
+	dst := []byte("prefix")
+	for name, fn := range map[string]func() ([]byte, error){
+		"AppendDataPacket universe 0": func() ([]byte, error) {
+			return cfg.AppendDataPacket(dst, NoSync, 0, 0, Universe{})
+		},
+		"AppendPriorityPacket without Priorities": func() ([]byte, error) {
+			return cfg.AppendPriorityPacket(dst, NoSync, 0, 0, Universe{Number: 1})
+		},
+		"AppendPriorityPacket universe 0": func() ([]byte, error) {
+			return cfg.AppendPriorityPacket(dst, NoSync, 0, 0, Universe{Priorities: new([512]byte)})
+		},
+	} {
+		got, err := fn()
+		if err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if string(got) != "prefix" || &got[0] != &dst[0] {
+			t.Errorf("%s: returned %q, want dst unchanged", name, got)
+		}
+	}
+}
+
+func TestStartCodePacket(t *testing.T) {
+	cfg := Config{SourceName: "test", Priority: 100}
+	for _, n := range []int{0, 1, 24, 512} {
+		payload := make([]byte, n)
+		for i := range payload {
+			payload[i] = byte(i)
+		}
+		b, err := cfg.StartCodePacket(NoSync, 0, 0, 0xcc, 1, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != dataPacketMinSize+n {
+			t.Errorf("%d-byte payload: packet is %d bytes, want %d", n, len(b), dataPacketMinSize+n)
+		}
+		f, err := ParseDataPacket(b)
+		if err != nil {
+			t.Fatalf("%d-byte payload: %v", n, err)
+		}
+		if f.StartCode != 0xcc || f.SlotCount != n || !bytes.Equal(f.Payload(), payload) {
+			t.Errorf("%d-byte payload: parsed START code %#02x with %d slots", n, f.StartCode, f.SlotCount)
+		}
+	}
+}
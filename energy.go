@@ -0,0 +1,159 @@
+package e131
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ChannelUsage is the estimated energy use of one channel.
+type ChannelUsage struct {
+	ChannelAddr
+	Label ChannelLabel
+	// WattHours is the energy used, assuming power proportional to level.
+	WattHours float64
+	// LampHours is the time the channel spent above zero, in hours.
+	LampHours float64
+}
+
+// EnergyStats summarizes the usage measured by an EnergyMeter.
+type EnergyStats struct {
+	// Since is when measuring started: creation or the last Reset.
+	Since time.Time
+	// WattHours is the total of the channels' WattHours.
+	WattHours float64
+	// Channels holds each metered channel, ordered by universe and channel.
+	Channels []ChannelUsage
+}
+
+// EnergyMeter estimates energy use and lamp hours by integrating channel
+// levels over time against the Watts of their labels. A channel's power is
+// taken to be proportional to its level, which suits dimmed incandescent
+// loads; other loads give a rough estimate only.
+//
+// Levels are held between updates, as DMX outputs hold theirs, so the meter
+// should be fed merged output that also reports sources going away, for
+// example with Receiver.SubscribeMerged.
+type EnergyMeter struct {
+	// channels maps universe numbers to their metered channels.
+	channels map[uint16][]*meteredChannel
+
+	mu    sync.Mutex
+	since time.Time
+}
+
+type meteredChannel struct {
+	usage ChannelUsage
+	level uint8
+	// at is when level was last integrated, or the zero time before the
+	// first update.
+	at time.Time
+}
+
+// NewEnergyMeter returns an EnergyMeter for the channels in labels with a
+// positive Watts.
+func NewEnergyMeter(labels Labels) *EnergyMeter {
+	m := &EnergyMeter{
+		channels: make(map[uint16][]*meteredChannel),
+		since:    time.Now(),
+	}
+	for addr, l := range labels {
+		if l.Watts <= 0 || addr.Channel < 0 || addr.Channel >= 512 {
+			continue
+		}
+		m.channels[addr.Universe] = append(m.channels[addr.Universe], &meteredChannel{
+			usage: ChannelUsage{ChannelAddr: addr, Label: l},
+		})
+	}
+	return m
+}
+
+// Update records the levels of u from now on.
+func (m *EnergyMeter) Update(u Universe) {
+	m.update(u, time.Now())
+}
+
+func (m *EnergyMeter) update(u Universe, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.channels[u.Number] {
+		c.integrate(now)
+		c.level = u.Slots[c.usage.Channel]
+	}
+}
+
+// integrate adds the usage at the current level up to now.
+func (c *meteredChannel) integrate(now time.Time) {
+	if !c.at.IsZero() && c.level > 0 {
+		hours := now.Sub(c.at).Hours()
+		c.usage.WattHours += c.usage.Label.Watts * float64(c.level) / 255 * hours
+		c.usage.LampHours += hours
+	}
+	c.at = now
+}
+
+// Stats returns the usage measured up to now.
+func (m *EnergyMeter) Stats() EnergyStats {
+	return m.stats(time.Now())
+}
+
+func (m *EnergyMeter) stats(now time.Time) EnergyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := EnergyStats{Since: m.since}
+	for _, channels := range m.channels {
+		for _, c := range channels {
+			c.integrate(now)
+			s.WattHours += c.usage.WattHours
+			s.Channels = append(s.Channels, c.usage)
+		}
+	}
+	sort.Slice(s.Channels, func(i, j int) bool {
+		a, b := s.Channels[i].ChannelAddr, s.Channels[j].ChannelAddr
+		if a.Universe != b.Universe {
+			return a.Universe < b.Universe
+		}
+		return a.Channel < b.Channel
+	})
+	return s
+}
+
+// Reset clears the measured usage, keeping the current levels.
+func (m *EnergyMeter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.since = now
+	for _, channels := range m.channels {
+		for _, c := range channels {
+			c.usage.WattHours = 0
+			c.usage.LampHours = 0
+			if !c.at.IsZero() {
+				c.at = now
+			}
+		}
+	}
+}
+
+// WriteCSV writes s as CSV with a header row and one row per channel. Channels
+// are numbered from 1, as DMX addresses.
+func (s EnergyStats) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"universe", "address", "name", "fixture", "watts", "watt_hours", "lamp_hours"})
+	for _, c := range s.Channels {
+		cw.Write([]string{
+			strconv.Itoa(int(c.Universe)),
+			strconv.Itoa(c.Channel + 1),
+			c.Label.Name,
+			c.Label.Fixture,
+			strconv.FormatFloat(c.Label.Watts, 'f', -1, 64),
+			strconv.FormatFloat(c.WattHours, 'f', 3, 64),
+			strconv.FormatFloat(c.LampHours, 'f', 3, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
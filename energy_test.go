@@ -0,0 +1,55 @@
+package e131
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnergyMeter(t *testing.T) {
+	m := NewEnergyMeter(Labels{
+		{Universe: 1, Channel: 0}:   {Name: "Key", Fixture: "Dimmer 1", Watts: 1000},
+		{Universe: 1, Channel: 1}:   {Name: "Unknown"},
+		{Universe: 1, Channel: 512}: {Name: "Beyond", Watts: 100},
+		{Universe: 2, Channel: 511}: {Name: "Fill", Watts: 500},
+	})
+	start := time.Now()
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	level := func(v uint8) Universe {
+		u := Universe{Number: 1}
+		u.Slots[0], u.Slots[1] = v, v
+		return u
+	}
+
+	m.update(level(255), at(0))
+	m.update(level(0), at(1))
+	m.update(level(51), at(2))
+	s := m.stats(at(3))
+	if len(s.Channels) != 2 || s.Channels[0].Channel != 0 || s.Channels[1].Universe != 2 {
+		t.Fatalf("metered %+v, want the two channels with Watts", s.Channels)
+	}
+	key := s.Channels[0]
+	if key.WattHours != 1200 || key.LampHours != 2 {
+		t.Errorf("full level for an hour, off for an hour and 20%% for an hour used %v Wh over %v lamp hours, want 1200 and 2",
+			key.WattHours, key.LampHours)
+	}
+	if s.WattHours != 1200 || s.Channels[1].WattHours != 0 {
+		t.Errorf("total %v Wh, want 1200 from the updated channel only", s.WattHours)
+	}
+
+	var csv strings.Builder
+	if err := s.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	want := "universe,address,name,fixture,watts,watt_hours,lamp_hours\n" +
+		"1,1,Key,Dimmer 1,1000,1200.000,2.000\n" +
+		"2,512,Fill,,500,0.000,0.000\n"
+	if csv.String() != want {
+		t.Errorf("CSV:\n%s\nwant:\n%s", csv.String(), want)
+	}
+
+	m.Reset()
+	if s := m.Stats(); s.WattHours > 1 || s.Since.Before(start) {
+		t.Errorf("after Reset used %v Wh since %v", s.WattHours, s.Since)
+	}
+}
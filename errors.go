@@ -0,0 +1,51 @@
+package e131
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors. Errors returned by the package match one of these with
+// errors.Is, while their messages give the details.
+var (
+	ErrSourceNameEmpty    = errors.New("Empty e131 Source Name")
+	ErrSourceNameTooLong  = errors.New("e131 Source Name longer than 63 bytes")
+	ErrPriorityOutOfRange = errors.New("Priority out of range")
+	ErrUniverseOutOfRange = errors.New("Universe out of range")
+	ErrChannelOutOfRange  = errors.New("Channel out of range")
+	ErrInvalidConfig      = errors.New("Invalid configuration")
+	ErrInvalidArgument    = errors.New("Invalid argument")
+
+	ErrShortPacket     = errors.New("Packet too short")
+	ErrBadLength       = errors.New("Packet length mismatch")
+	ErrBadHeader       = errors.New("Invalid root layer header")
+	ErrBadVector       = errors.New("Invalid vector")
+	ErrBadDMP          = errors.New("Invalid DMP layer")
+	ErrWrongPacketType = errors.New("Wrong packet type")
+
+	ErrClosed           = errors.New("Closed")
+	ErrClaimed          = errors.New("Universe claimed by another writer")
+	ErrLeaseReleased    = errors.New("Lease was released")
+	ErrWrongUniverse    = errors.New("Universe does not match lease")
+	ErrLimitExceeded    = errors.New("Sender limit reached")
+	ErrNoPriorities     = errors.New("Universe has no Priorities")
+	ErrOverlap          = errors.New("Composite channels overlap")
+	ErrTooManyUniverses = errors.New("Too many universes")
+	ErrNoIPv4Address    = errors.New("Interface has no IPv4 address")
+	ErrUnsupported      = errors.New("Not supported on this platform")
+)
+
+// detailError is an error with its own message that matches a sentinel.
+type detailError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *detailError) Error() string { return e.msg }
+func (e *detailError) Unwrap() error { return e.sentinel }
+
+// errorf returns an error formatted like fmt.Errorf that matches sentinel
+// with errors.Is.
+func errorf(sentinel error, format string, args ...interface{}) error {
+	return &detailError{msg: fmt.Sprintf(format, args...), sentinel: sentinel}
+}
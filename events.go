@@ -0,0 +1,206 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"sync"
+)
+
+// EventKind identifies a kind of Event. Kinds are bits, so that an
+// EventFilter can select several.
+type EventKind uint
+
+// Kinds of Event.
+const (
+	EventSourceFound EventKind = 1 << iota
+	EventSourceLost
+	EventPreemption
+	EventSyncLost
+	EventParseError
+	EventSocketError
+	EventDiscovery
+	EventPinMismatch
+	EventUnexpectedStartCode
+	EventStreamTransition
+)
+
+// Event is a notification published on an EventBus. It is one of
+// SourceFound, SourceLost, StreamTransition, Preemption, SyncLost,
+// ParseError, SocketError, DiscoveryEvent, PinMismatch or
+// UnexpectedStartCode; use a type switch to tell them apart.
+type Event interface {
+	Kind() EventKind
+	// universe returns the universe the event is about, or false if it is
+	// not about one universe.
+	universe() (uint16, bool)
+}
+
+// SourceFound reports that a Receiver has started receiving a source on a
+// universe.
+type SourceFound struct {
+	Universe   uint16
+	CID        uuid.UUID
+	SourceName string
+}
+
+// SyncLost reports that the synchronization packets for a synchronization
+// address a Receiver was following have stopped for DataLossTimeout.
+type SyncLost struct {
+	SyncAddr uint16
+}
+
+// ParseError reports a packet for a received universe that could not be
+// decoded.
+type ParseError struct {
+	Universe uint16
+	// Addr is where the packet came from, or nil if the transport does not
+	// report it.
+	Addr net.Addr
+	Err  error
+}
+
+// SocketError reports a failed read from a Receiver's transport. The
+// Receiver keeps reading.
+type SocketError struct {
+	Err error
+}
+
+func (SourceFound) Kind() EventKind         { return EventSourceFound }
+func (SourceLost) Kind() EventKind          { return EventSourceLost }
+func (Preemption) Kind() EventKind          { return EventPreemption }
+func (SyncLost) Kind() EventKind            { return EventSyncLost }
+func (ParseError) Kind() EventKind          { return EventParseError }
+func (SocketError) Kind() EventKind         { return EventSocketError }
+func (DiscoveryEvent) Kind() EventKind      { return EventDiscovery }
+func (PinMismatch) Kind() EventKind         { return EventPinMismatch }
+func (UnexpectedStartCode) Kind() EventKind { return EventUnexpectedStartCode }
+func (StreamTransition) Kind() EventKind    { return EventStreamTransition }
+
+func (e SourceFound) universe() (uint16, bool)         { return e.Universe, true }
+func (e SourceLost) universe() (uint16, bool)          { return e.Universe, true }
+func (e Preemption) universe() (uint16, bool)          { return e.Universe, true }
+func (e SyncLost) universe() (uint16, bool)            { return e.SyncAddr, true }
+func (e ParseError) universe() (uint16, bool)          { return e.Universe, true }
+func (SocketError) universe() (uint16, bool)           { return 0, false }
+func (DiscoveryEvent) universe() (uint16, bool)        { return 0, false }
+func (e PinMismatch) universe() (uint16, bool)         { return e.Universe, true }
+func (e UnexpectedStartCode) universe() (uint16, bool) { return e.Universe, true }
+func (e StreamTransition) universe() (uint16, bool)    { return e.Universe, true }
+
+// EventFilter selects the events an EventBus subscription receives.
+type EventFilter struct {
+	// Kinds selects the kinds of event, or every kind if it is 0.
+	Kinds EventKind
+	// Universes, if not empty, limits events about a universe to those
+	// listed. Events not about one universe, such as socket errors and
+	// discovery changes, are not filtered by universe.
+	Universes []uint16
+}
+
+// match reports whether e passes the filter.
+func (f EventFilter) match(e Event) bool {
+	if f.Kinds != 0 && f.Kinds&e.Kind() == 0 {
+		return false
+	}
+	u, ok := e.universe()
+	if !ok || len(f.Universes) == 0 {
+		return true
+	}
+	for _, v := range f.Universes {
+		if v == u {
+			return true
+		}
+	}
+	return false
+}
+
+// EventBus delivers the notifications of the Receivers, Mergers and
+// DiscoveryListeners it is attached to, with SetEventBus, to its
+// subscribers. One bus can serve several of them. Events are delivered
+// synchronously, from the goroutine that publishes them, outside the
+// publisher's locks; a subscriber that blocks delays its publisher.
+type EventBus struct {
+	mu   sync.Mutex
+	subs []*EventSubscription
+	// kinds is the union of the kinds subscribed to, so that publishers
+	// can skip work nobody listens for.
+	kinds EventKind
+}
+
+// EventSubscription is one subscriber to an EventBus.
+type EventSubscription struct {
+	bus    *EventBus
+	filter EventFilter
+	fn     func(Event)
+}
+
+// NewEventBus returns an EventBus with no subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe calls fn for every event published on the bus that passes
+// filter.
+func (b *EventBus) Subscribe(filter EventFilter, fn func(Event)) *EventSubscription {
+	sub := &EventSubscription{bus: b, filter: filter, fn: fn}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(append([]*EventSubscription(nil), b.subs...), sub)
+	b.updateKinds()
+	return sub
+}
+
+// Cancel stops the subscription. fn may still be running, or be called
+// once more, for an event published concurrently with Cancel.
+func (sub *EventSubscription) Cancel() {
+	b := sub.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var subs []*EventSubscription
+	for _, s := range b.subs {
+		if s != sub {
+			subs = append(subs, s)
+		}
+	}
+	b.subs = subs
+	b.updateKinds()
+}
+
+// updateKinds recomputes b.kinds, with b.mu held.
+func (b *EventBus) updateKinds() {
+	b.kinds = 0
+	for _, s := range b.subs {
+		if s.filter.Kinds == 0 {
+			b.kinds = ^EventKind(0)
+			return
+		}
+		b.kinds |= s.filter.Kinds
+	}
+}
+
+// wants reports whether anyone subscribes to events of kind. It is false for
+// a nil bus.
+func (b *EventBus) wants(kind EventKind) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.kinds&kind != 0
+}
+
+// publish delivers e to the matching subscribers. It does nothing on a nil
+// bus.
+func (b *EventBus) publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	subs := b.subs
+	b.mu.Unlock()
+	for _, s := range subs {
+		if s.filter.match(e) {
+			s.fn(e)
+		}
+	}
+}
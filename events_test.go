@@ -0,0 +1,146 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEventFilter(t *testing.T) {
+	bus := NewEventBus()
+	var all, lost, one []Event
+	bus.Subscribe(EventFilter{}, func(e Event) { all = append(all, e) })
+	bus.Subscribe(EventFilter{Kinds: EventSourceLost | EventSyncLost}, func(e Event) { lost = append(lost, e) })
+	sub := bus.Subscribe(EventFilter{Universes: []uint16{2}}, func(e Event) { one = append(one, e) })
+
+	if !bus.wants(EventParseError) {
+		t.Error("bus with an unfiltered subscriber does not want parse errors")
+	}
+	bus.publish(SourceFound{Universe: 1})
+	bus.publish(SourceLost{Universe: 2})
+	bus.publish(SyncLost{SyncAddr: 3})
+	bus.publish(SocketError{})
+	sub.Cancel()
+	bus.publish(SourceLost{Universe: 2})
+
+	if len(all) != 5 {
+		t.Errorf("unfiltered subscriber got %d events, want 5", len(all))
+	}
+	if len(lost) != 3 {
+		t.Errorf("kind filter passed %d events, want 3", len(lost))
+	}
+	if len(one) != 2 || one[0] != (SourceLost{Universe: 2}) || one[1] != (SocketError{}) {
+		t.Errorf("universe filter passed %v, want the universe 2 loss and the socket error", one)
+	}
+}
+
+func TestEventBusKinds(t *testing.T) {
+	var bus *EventBus
+	if bus.wants(EventSourceFound) {
+		t.Error("nil bus wants events")
+	}
+	bus.publish(SourceFound{})
+
+	bus = NewEventBus()
+	sub := bus.Subscribe(EventFilter{Kinds: EventPreemption}, func(Event) {})
+	if !bus.wants(EventPreemption) || bus.wants(EventSourceFound) {
+		t.Error("wants does not follow the subscribed kinds")
+	}
+	sub.Cancel()
+	if bus.wants(EventPreemption) {
+		t.Error("bus wants events after its only subscription is cancelled")
+	}
+}
+
+func TestReceiverEvents(t *testing.T) {
+	c := newMemConn()
+	r := NewReceiverConn(c, func(DataFrame) {})
+	defer r.Close()
+	events := make(chan Event, 16)
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventSourceFound | EventSourceLost | EventParseError}, func(e Event) { events <- e })
+	r.SetEventBus(bus)
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{CID: uuid.NewV4(), SourceName: "events", Priority: 100}
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: Port}
+	packet := func(options byte) []byte {
+		b, err := cfg.DataPacket(NoSync, 0, options, Universe{Number: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	bad := packet(0)
+	bad[117] = 0
+	c.deliver(packet(0), addr)
+	c.deliver(bad, addr)
+	c.deliver(packet(byte(StreamTerminated)), addr)
+
+	next := func() Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+	if e, ok := next().(SourceFound); !ok || e.CID != cfg.CID || e.SourceName != "events" {
+		t.Errorf("first event %+v, want the source found", e)
+	}
+	if e, ok := next().(ParseError); !ok || e.Universe != 1 || e.Addr != addr || e.Err == nil {
+		t.Errorf("second event %+v, want a parse error", e)
+	}
+	if e, ok := next().(SourceLost); !ok || e.CID != cfg.CID || !e.Terminated {
+		t.Errorf("third event %+v, want the source terminated", e)
+	}
+}
+
+func TestSyncLostEvent(t *testing.T) {
+	r := NewReceiverConn(newMemConn(), func(DataFrame) {})
+	defer r.Close()
+	var got []Event
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventSyncLost}, func(e Event) { got = append(got, e) })
+	r.SetEventBus(bus)
+	r.SetSynchronized(true)
+
+	now := time.Now()
+	r.release(7, now)
+	r.expire(now.Add(DataLossTimeout / 2))
+	later := now.Add(DataLossTimeout + time.Millisecond)
+	r.expire(later)
+	r.expire(later.Add(time.Second))
+	if len(got) != 1 || got[0] != (SyncLost{SyncAddr: 7}) {
+		t.Fatalf("got %v, want synchronization 7 lost once", got)
+	}
+
+	r.release(7, later)
+	r.expire(later.Add(DataLossTimeout + time.Millisecond))
+	if len(got) != 2 {
+		t.Errorf("loss after synchronization resumed reported %d times in all, want 2", len(got))
+	}
+}
+
+func TestMergerEvents(t *testing.T) {
+	m := NewMerger(HTP)
+	var got []Event
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventPreemption, Universes: []uint16{1}}, func(e Event) { got = append(got, e) })
+	m.SetEventBus(bus)
+
+	frames := sourceFrames(2)
+	frames[1].Priority = 150
+	m.Update(frames[0])
+	m.Update(frames[1])
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	if p, ok := got[0].(Preemption); !ok || p.Loser != frames[0].CID || p.Winner != frames[1].CID {
+		t.Errorf("got %+v", got[0])
+	}
+}
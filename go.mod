@@ -0,0 +1,5 @@
+module github.com/jagipson/e131
+
+go 1.20
+
+require github.com/satori/go.uuid v1.2.0
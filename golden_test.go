@@ -0,0 +1,120 @@
+// The golden-file tests check the codec against reference packets. Every .bin
+// file under ratified/ must parse and, where the encoder can produce the same
+// packet, re-encode to identical bytes; every file under draft/ must be
+// rejected. The files in testdata/golden are written by "sacn genfixtures",
+// which lays packets out from the specification rather than with the
+// package's encoder. They are not captures from other implementations, so
+// passing shows the codec matches that reference, not that it interoperates
+// with sACNView, OLA or consoles. Captures from those can be checked by
+// laying them out the same way in another directory:
+//
+//	go test -run Golden -golden dir
+
+package e131
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var goldenDir = flag.String("golden", "testdata/golden", "directory holding ratified/ and draft/ packets")
+
+// goldenFiles returns the .bin files in the sub directory of the golden
+// directory, skipping the test if there are none.
+func goldenFiles(t *testing.T, sub string) []string {
+	files, err := filepath.Glob(filepath.Join(*goldenDir, sub, "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skipf("no fixtures in %s", filepath.Join(*goldenDir, sub))
+	}
+	return files
+}
+
+// reencode builds the packet b decodes to. It returns nil for packets the
+// encoder cannot produce: data packets with fewer than 512 slots or an
+// alternate START code.
+func reencode(t *testing.T, b []byte) []byte {
+	var packet []byte
+	var err error
+	switch Classify(b) {
+	case PacketData:
+		f, perr := ParseDataPacket(b)
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		cfg := Config{CID: f.CID, SourceName: f.SourceName, Priority: f.Priority}
+		switch {
+		case f.SlotCount != 512:
+			return nil
+		case f.StartCode == NullStartCode:
+			packet, err = dataPacket(nil, &cfg, f.SyncAddr, f.Sequence, f.Options, f.Universe)
+		case f.StartCode == PriorityStartCode:
+			packet, err = priorityPacket(nil, &cfg, f.SyncAddr, f.Sequence, f.Options, f.Universe)
+		default:
+			return nil
+		}
+	case PacketSync:
+		f, perr := ParseSyncPacket(b)
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		packet, err = syncPacket(nil, &Config{CID: f.CID}, f.SyncAddr, f.Sequence)
+	case PacketDiscovery:
+		f, perr := ParseDiscoveryPacket(b)
+		if perr != nil {
+			t.Fatal(perr)
+		}
+		universes := make([]Universe, len(f.Universes))
+		for i, u := range f.Universes {
+			universes[i].Number = u
+		}
+		cfg := Config{CID: f.CID, SourceName: f.SourceName}
+		packet, err = discPacket(&cfg, f.Page, f.LastPage, universes)
+	default:
+		t.Fatalf("unknown packet type")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packet
+}
+
+func TestGoldenRatified(t *testing.T) {
+	for _, name := range goldenFiles(t, "ratified") {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Validate(b); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			got := reencode(t, b)
+			if got == nil {
+				t.Skip("the encoder cannot produce this packet")
+			}
+			if !bytes.Equal(got, b) {
+				t.Errorf("re-encoded packet differs\ngot  % x\nwant % x", got, b)
+			}
+		})
+	}
+}
+
+func TestGoldenDraft(t *testing.T) {
+	for _, name := range goldenFiles(t, "draft") {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Validate(b); err == nil {
+				t.Error("Validate accepted a draft packet")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package e131
+
+import (
+	"net"
+)
+
+// Interface is a local network interface that can carry sACN multicast.
+type Interface struct {
+	net.Interface
+	// Addrs are the unicast addresses assigned to the interface.
+	Addrs []net.IP
+	// Bindable reports whether a UDP socket could be bound to Port on at
+	// least one of Addrs, i.e. no other process holds the port exclusively.
+	Bindable bool
+}
+
+// Interfaces lists the local network interfaces that are up and multicast
+// capable, for presenting valid choices in configuration UIs.
+func Interfaces() ([]Interface, error) {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Interface
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		i := Interface{Interface: ifi}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			i.Addrs = append(i.Addrs, ipnet.IP)
+			if !i.Bindable {
+				i.Bindable = canBind(ifi, ipnet.IP)
+			}
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// interfaceIPv4 returns the first IPv4 address assigned to ifi.
+func interfaceIPv4(ifi *net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if ip := ipnet.IP.To4(); ip != nil {
+				return ip, nil
+			}
+		}
+	}
+	return nil, errorf(ErrNoIPv4Address, "Interface %s has no IPv4 address", ifi.Name)
+}
+
+// canBind reports whether a UDP socket can be bound to ip:Port.
+func canBind(ifi net.Interface, ip net.IP) bool {
+	addr := &net.UDPAddr{IP: ip, Port: Port}
+	if ip.IsLinkLocalUnicast() && ip.To4() == nil {
+		addr.Zone = ifi.Name
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,88 @@
+package e131
+
+import (
+	"time"
+)
+
+// keepAliveInterval returns how often universe is kept alive, or 0 if it is
+// not; see Config.UniverseKeepAlive and Config.DestinationKeepAlive.
+func (c *Config) keepAliveInterval(universe uint16) time.Duration {
+	interval := c.KeepAlive
+	if d, ok := c.UniverseKeepAlive[universe]; ok {
+		interval = d
+	}
+	for _, addr := range c.Unicast[universe] {
+		if d := c.DestinationKeepAlive[addr.String()]; d > 0 && (interval == 0 || d < interval) {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// shortestKeepAlive returns the shortest keep-alive interval c sets, or 0 if it
+// keeps nothing alive.
+func (c *Config) shortestKeepAlive() time.Duration {
+	shortest := c.KeepAlive
+	shorter := func(d time.Duration) {
+		if d > 0 && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	for _, d := range c.UniverseKeepAlive {
+		shorter(d)
+	}
+	for _, d := range c.DestinationKeepAlive {
+		shorter(d)
+	}
+	return shortest
+}
+
+// keepAlive retransmits the last packet of each universe once its keep-alive
+// interval has passed without it being sent, until the Sender is closed.
+// shortest is the shortest interval of any universe.
+func (s *Sender) keepAlive(shortest time.Duration) {
+	timer := time.NewTimer(shortest)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-timer.C:
+			s.mu.Lock()
+			now := time.Now()
+			next := s.keepAliveLocked(now, shortest)
+			s.mu.Unlock()
+			timer.Reset(next.Sub(now))
+		}
+	}
+}
+
+// keepAliveLocked retransmits, with s.mu held, each packet last sent its
+// universe's keep-alive interval or more before now, and returns when the
+// next one falls due. Packets sent later are due no sooner than
+// now+shortest, so the returned time stays valid until it passes.
+func (s *Sender) keepAliveLocked(now time.Time, shortest time.Duration) time.Time {
+	next := now.Add(shortest)
+	for key, l := range s.last {
+		interval, ok := s.intervals[key.universe]
+		if !ok {
+			interval = s.cfg.keepAliveInterval(key.universe)
+			s.intervals[key.universe] = interval
+		}
+		if interval == 0 || s.paused[key.universe] {
+			continue
+		}
+		if due := l.at.Add(interval); now.Before(due) {
+			if due.Before(next) {
+				next = due
+			}
+			continue
+		}
+		if s.claims[key.universe] != l.lease {
+			delete(s.last, key)
+			continue
+		}
+		s.sendLocked(l.lease, l.build, l.syncAddr, l.optionsFlags, l.universe, now)
+	}
+	return next
+}
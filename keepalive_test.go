@@ -0,0 +1,147 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveConfig(t *testing.T) {
+	for _, d := range []time.Duration{-1, 1, MinKeepAlive - 1} {
+		cfg := Config{SourceName: "test", KeepAlive: d}
+		if _, err := NewSenderConn(cfg, nopConn{}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("KeepAlive %v: got %v, want ErrInvalidConfig", d, err)
+		}
+	}
+}
+
+// TestKeepAliveSchedule checks that each universe is retransmitted exactly
+// KeepAlive after it was last sent.
+func TestKeepAliveSchedule(t *testing.T) {
+	const interval = time.Second
+	s, c := testSender(t, Config{KeepAlive: interval})
+	s.Send(0, Universe{Number: 1})
+	s.Send(0, Universe{Number: 2})
+	base := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last[lastKey{1, NullStartCode}].at = base
+	s.last[lastKey{2, NullStartCode}].at = base.Add(300 * time.Millisecond)
+
+	for _, step := range []struct {
+		now      time.Duration
+		universe uint16 // resent, or 0 for none
+		next     time.Duration
+	}{
+		{999 * time.Millisecond, 0, 1000 * time.Millisecond},
+		{1000 * time.Millisecond, 1, 1300 * time.Millisecond},
+		{1300 * time.Millisecond, 2, 2000 * time.Millisecond},
+		{1500 * time.Millisecond, 0, 2000 * time.Millisecond},
+	} {
+		before := len(c.packets())
+		next := s.keepAliveLocked(base.Add(step.now), interval)
+		if want := base.Add(step.next); !next.Equal(want) {
+			t.Errorf("at %v: next keep-alive at %v, want %v", step.now, next.Sub(base), step.next)
+		}
+		sent := sentFrames(t, c.packets()[before:])
+		switch {
+		case step.universe == 0 && len(sent) != 0:
+			t.Errorf("at %v: resent %d packets, want none", step.now, len(sent))
+		case step.universe != 0 && (len(sent) != 1 || sent[0].Universe.Number != step.universe):
+			t.Errorf("at %v: resent %d packets, want universe %d", step.now, len(sent), step.universe)
+		}
+	}
+}
+
+func TestKeepAliveTerminate(t *testing.T) {
+	const interval = 2 * MinKeepAlive
+	s, c := testSender(t, Config{KeepAlive: interval})
+	s.Send(0, Universe{Number: 1})
+	deadline := time.Now().Add(time.Second)
+	for len(c.packets()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(interval / 4)
+	}
+	packets := c.packets()
+	if len(packets) < 3 {
+		t.Fatalf("got %d packets, want keep-alives", len(packets))
+	}
+	for i := 1; i < len(packets); i++ {
+		if gap := packets[i].at.Sub(packets[i-1].at); gap < interval {
+			t.Errorf("keep-alive %d sent %v after the last packet, want at least %v", i, gap, interval)
+		}
+	}
+
+	if err := s.Terminate(1); err != nil {
+		t.Fatal(err)
+	}
+	n := len(c.packets())
+	time.Sleep(3 * interval)
+	if extra := len(c.packets()) - n; extra != 0 {
+		t.Errorf("sent %d packets after termination", extra)
+	}
+}
+
+func TestUniverseKeepAlive(t *testing.T) {
+	dest := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: Port}
+	s, c := testSender(t, Config{
+		KeepAlive:            time.Second,
+		UniverseKeepAlive:    map[uint16]time.Duration{2: 200 * time.Millisecond, 3: 0},
+		Unicast:              map[uint16][]*net.UDPAddr{4: {dest}},
+		DestinationKeepAlive: map[string]time.Duration{dest.String(): 300 * time.Millisecond},
+	})
+	want := map[uint16]time.Duration{1: time.Second, 2: 200 * time.Millisecond, 3: 0, 4: 300 * time.Millisecond}
+	for u, interval := range want {
+		if got := s.cfg.keepAliveInterval(u); got != interval {
+			t.Errorf("universe %d kept alive every %v, want %v", u, got, interval)
+		}
+		s.Send(0, Universe{Number: u})
+	}
+
+	base := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range s.last {
+		l.at = base
+	}
+	// Universe 3 is never kept alive.
+	for _, step := range []struct {
+		at     time.Duration
+		resent []uint16
+	}{
+		{200 * time.Millisecond, []uint16{2}},
+		{300 * time.Millisecond, []uint16{4}},
+		{400 * time.Millisecond, []uint16{2}},
+		{time.Second, []uint16{1, 2, 4}},
+	} {
+		before := len(c.packets())
+		s.keepAliveLocked(base.Add(step.at), 200*time.Millisecond)
+		var resent []uint16
+		for _, f := range sentFrames(t, c.packets()[before:]) {
+			resent = append(resent, f.Universe.Number)
+		}
+		sort.Slice(resent, func(i, j int) bool { return resent[i] < resent[j] })
+		if !equalUniverses(resent, step.resent) {
+			t.Errorf("at %v: resent universes %v, want %v", step.at, resent, step.resent)
+		}
+	}
+}
+
+func TestUniverseKeepAliveConfig(t *testing.T) {
+	for _, opt := range []Option{
+		WithUniverseKeepAlive(0, time.Second),
+		WithUniverseKeepAlive(1, time.Millisecond),
+		WithDestinationKeepAlive(nil, time.Second),
+		WithDestinationKeepAlive(&net.UDPAddr{}, -time.Second),
+	} {
+		var cfg Config
+		if err := opt(&cfg); !errors.Is(err, ErrInvalidConfig) && !errors.Is(err, ErrUniverseOutOfRange) {
+			t.Errorf("got %v, want an error", err)
+		}
+	}
+	cfg := Config{SourceName: "test", UniverseKeepAlive: map[uint16]time.Duration{1: time.Millisecond}}
+	if _, err := NewSenderConn(cfg, nopConn{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("UniverseKeepAlive below MinKeepAlive: got %v", err)
+	}
+}
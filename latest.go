@@ -0,0 +1,65 @@
+package e131
+
+import (
+	"encoding/binary"
+	"runtime"
+	"sync/atomic"
+)
+
+// latestFrame is a single-writer seqlock holding the slots of one universe.
+// Readers never block the writer; they retry if a write overlapped their
+// read. The slots are stored as atomic words so that concurrent access is
+// well defined.
+type latestFrame struct {
+	seq   atomic.Uint32
+	words [128]atomic.Uint32
+}
+
+// store publishes slots. Only one goroutine may call store at a time.
+func (l *latestFrame) store(slots *[512]byte) {
+	l.seq.Add(1) // odd: write in progress
+	for i := range l.words {
+		l.words[i].Store(binary.LittleEndian.Uint32(slots[i*4:]))
+	}
+	l.seq.Add(1)
+}
+
+// load copies the most recently published slots into slots.
+func (l *latestFrame) load(slots *[512]byte) {
+	for {
+		seq := l.seq.Load()
+		if seq&1 != 0 {
+			runtime.Gosched()
+			continue
+		}
+		for i := range l.words {
+			binary.LittleEndian.PutUint32(slots[i*4:], l.words[i].Load())
+		}
+		if l.seq.Load() == seq {
+			return
+		}
+	}
+}
+
+// publish makes mu.output visible to Latest.
+func (m *Merger) publish(mu *mergeUniverse) {
+	v, ok := m.latest.Load(mu.output.Number)
+	if !ok {
+		v, _ = m.latest.LoadOrStore(mu.output.Number, new(latestFrame))
+	}
+	v.(*latestFrame).store(&mu.output.Slots)
+}
+
+// Latest returns the most recent merged output for universe without taking
+// the Merger's lock, so renderers can poll it at any rate without delaying
+// the goroutine calling Update. The second result is false if nothing has
+// been merged for universe yet.
+func (m *Merger) Latest(universe uint16) (Universe, bool) {
+	u := Universe{Number: universe}
+	v, ok := m.latest.Load(universe)
+	if !ok {
+		return u, false
+	}
+	v.(*latestFrame).load(&u.Slots)
+	return u, true
+}
@@ -0,0 +1,60 @@
+package e131
+
+import (
+	"testing"
+)
+
+func TestLatest(t *testing.T) {
+	m := NewMerger(HTP)
+	if _, ok := m.Latest(1); ok {
+		t.Error("output reported before any merge")
+	}
+	frames := sourceFrames(2)
+	m.Update(frames[0])
+	want := m.Update(frames[1])
+	if got, ok := m.Latest(1); !ok || got != want {
+		t.Errorf("Latest returned %v, want the last merged output", got.Slots[:4])
+	}
+	want = m.Remove(1, frames[1].CID)
+	if got, _ := m.Latest(1); got != want {
+		t.Errorf("Latest returned %v after Remove, want %v", got.Slots[:4], want.Slots[:4])
+	}
+	if _, ok := m.Latest(2); ok {
+		t.Error("output reported for another universe")
+	}
+}
+
+// TestLatestConsistent reads Latest while the output changes and checks that
+// no read mixes two outputs.
+func TestLatestConsistent(t *testing.T) {
+	m := NewMerger(HTP)
+	f := sourceFrames(1)[0]
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2000; i++ {
+			for j := range f.Universe.Slots {
+				f.Universe.Slots[j] = byte(i)
+			}
+			m.Update(f)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		u, ok := m.Latest(1)
+		if !ok {
+			continue
+		}
+		for _, v := range u.Slots {
+			if v != u.Slots[0] {
+				t.Errorf("torn read: slots %d and %d in one output", u.Slots[0], v)
+				<-done
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package e131
+
+import (
+	"time"
+)
+
+// Limits caps what a Sender will transmit, protecting the network from
+// runaway callers. Sends that would exceed a limit fail instead of being
+// transmitted. A zero field means no limit.
+type Limits struct {
+	// MaxPacketsPerSecond caps the packets written per second, counting
+	// each unicast destination separately.
+	MaxPacketsPerSecond int
+	// MaxBytesPerSecond caps the E1.31 bytes written per second.
+	MaxBytesPerSecond int
+	// MaxUniverses caps the number of distinct universes sent.
+	MaxUniverses int
+}
+
+// check returns an error if any limit is negative.
+func (l Limits) check() error {
+	if l.MaxPacketsPerSecond < 0 || l.MaxBytesPerSecond < 0 || l.MaxUniverses < 0 {
+		return errorf(ErrInvalidConfig, "Unable to set Limits (out of bounds)")
+	}
+	return nil
+}
+
+// rateWindow counts the traffic sent in the current one-second window.
+type rateWindow struct {
+	start   time.Time
+	packets int
+	bytes   int
+}
+
+// allow records packets writes of size bytes at now, or returns an error
+// without recording them if that would exceed lim.
+func (w *rateWindow) allow(lim Limits, now time.Time, packets, size int) error {
+	if now.Sub(w.start) >= time.Second {
+		*w = rateWindow{start: now}
+	}
+	if lim.MaxPacketsPerSecond > 0 && w.packets+packets > lim.MaxPacketsPerSecond {
+		return errorf(ErrLimitExceeded, "Cannot send: limit of %d packets per second reached", lim.MaxPacketsPerSecond)
+	}
+	if lim.MaxBytesPerSecond > 0 && w.bytes+packets*size > lim.MaxBytesPerSecond {
+		return errorf(ErrLimitExceeded, "Cannot send: limit of %d bytes per second reached", lim.MaxBytesPerSecond)
+	}
+	w.packets += packets
+	w.bytes += packets * size
+	return nil
+}
@@ -0,0 +1,119 @@
+package e131
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// loopSettle is how long LoopTest waits for stragglers after the last send.
+const loopSettle = time.Second
+
+// LoopReport is the outcome of a LoopTest.
+type LoopReport struct {
+	Sent      int
+	Received  int // arrived with the signature intact
+	Corrupted int // arrived with a frame index but a damaged signature
+	Lost      int // never arrived
+}
+
+// Ok reports whether every frame came back intact.
+func (r LoopReport) Ok() bool {
+	return r.Received == r.Sent
+}
+
+// loopSignature returns the pattern sent as frame i of a loop test. The first
+// two slots carry i so that frames can be matched even if the return path
+// renumbers sequences.
+func loopSignature(i uint16) [512]byte {
+	var slots [512]byte
+	binary.BigEndian.PutUint16(slots[:2], i)
+	for j := 2; j < len(slots); j++ {
+		slots[j] = byte(int(i)*31 + j*7)
+	}
+	return slots
+}
+
+// maxLoopFrames is the most frames a LoopTest can tell apart by the index
+// carried in their first two slots.
+const maxLoopFrames = 1 << 16
+
+// LoopTest sends count signature frames on universe out through s, one every
+// interval, and verifies that each comes back unchanged on universe ret via a
+// physical loopback or mirroring node. count may be at most 65536 and
+// interval must be positive. Frames come back over the IP version and on the
+// interface that s sends with.
+func LoopTest(s *Sender, out, ret uint16, count int, interval time.Duration) (LoopReport, error) {
+	if count < 0 || count > maxLoopFrames {
+		return LoopReport{}, errorf(ErrInvalidArgument, "Cannot loop test %d frames (0-%d)", count, maxLoopFrames)
+	}
+	if interval <= 0 {
+		return LoopReport{}, errorf(ErrInvalidArgument, "Cannot loop test with interval %v (must be positive)", interval)
+	}
+	frames := make(chan DataFrame, 64)
+	done := make(chan struct{})
+	handler := func(f DataFrame) {
+		select {
+		case frames <- f:
+		case <-done:
+		}
+	}
+	var r *Receiver
+	if cfg := s.Config(); cfg.IPv6 {
+		r = NewReceiver6(handler)
+		r.SetInterface(cfg.Interface)
+	} else {
+		r = NewReceiver(handler)
+		r.SetInterface(cfg.Interface)
+	}
+	defer r.Close()
+	if err := r.Join(ret); err != nil {
+		return LoopReport{}, err
+	}
+	defer close(done)
+
+	var report LoopReport
+	seen := make(map[uint16]bool)
+	check := func(f DataFrame) {
+		i := binary.BigEndian.Uint16(f.Universe.Slots[:2])
+		if int(i) >= report.Sent || seen[i] {
+			return
+		}
+		seen[i] = true
+		if f.Universe.Slots == loopSignature(i) {
+			report.Received++
+		} else {
+			report.Corrupted++
+		}
+	}
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for report.Sent < count {
+		u := Universe{Slots: loopSignature(uint16(report.Sent)), Number: out}
+		if err := s.SendSynced(NoSync, 0, u); err != nil {
+			return report, err
+		}
+		report.Sent++
+	wait:
+		for {
+			select {
+			case f := <-frames:
+				check(f)
+			case <-tick.C:
+				break wait
+			}
+		}
+	}
+
+	settle := time.After(loopSettle)
+	for len(seen) < report.Sent {
+		select {
+		case f := <-frames:
+			check(f)
+		case <-settle:
+			report.Lost = report.Sent - len(seen)
+			return report, nil
+		}
+	}
+	return report, nil
+}
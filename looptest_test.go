@@ -0,0 +1,69 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLoopTestArguments(t *testing.T) {
+	s, conn := testSender(t, Config{})
+	for _, tc := range []struct {
+		count    int
+		interval time.Duration
+	}{
+		{-1, time.Millisecond},
+		{maxLoopFrames + 1, time.Millisecond},
+		{1, 0},
+		{1, -time.Second},
+	} {
+		if _, err := LoopTest(s, 1, 1, tc.count, tc.interval); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("LoopTest(count %d, interval %v): got %v, want ErrInvalidArgument", tc.count, tc.interval, err)
+		}
+	}
+	if n := len(conn.packets()); n != 0 {
+		t.Errorf("sent %d packets for invalid arguments", n)
+	}
+}
+
+// TestLoopTestIPv6 loops frames back through the host's own IPv6 multicast,
+// which needs the return path to follow the Sender's Config.
+func TestLoopTestIPv6(t *testing.T) {
+	s, err := NewSender(Config{SourceName: "test", IPv6: true, Interface: multicastInterface(t)})
+	if err != nil {
+		t.Skipf("no IPv6 multicast: %v", err)
+	}
+	defer s.Close()
+	report, err := LoopTest(s, 1, 1, 5, 10*time.Millisecond)
+	if err != nil {
+		t.Skipf("no IPv6 multicast: %v", err)
+	}
+	if !report.Ok() {
+		t.Errorf("got %+v, want every frame back", report)
+	}
+}
+
+// multicastInterface returns an interface that is up and multicast capable,
+// preferring loopback, or skips the test. Multicast sent from it is looped
+// back to the host.
+func multicastInterface(t *testing.T) *net.Interface {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	var found *net.Interface
+	for i := range ifis {
+		f := ifis[i].Flags
+		if f&net.FlagUp == 0 || f&net.FlagMulticast == 0 {
+			continue
+		}
+		if found == nil || f&net.FlagLoopback != 0 {
+			found = &ifis[i]
+		}
+	}
+	if found == nil {
+		t.Skip("no multicast interface")
+	}
+	return found
+}
@@ -0,0 +1,417 @@
+package e131
+
+import (
+	"bytes"
+	"encoding/binary"
+	uuid "github.com/satori/go.uuid"
+	"math"
+	"sync"
+)
+
+// MergeMode selects how a Merger resolves slots driven by more than one
+// source at the same priority.
+type MergeMode int
+
+const (
+	// HTP gives each slot the highest level among the competing sources.
+	HTP MergeMode = iota
+	// LTP gives each slot the level from the source that sent most recently.
+	LTP
+)
+
+// Merger combines the data received from multiple sources into one output
+// per universe. Following E1.31, only the sources with the highest priority
+// for a slot take part; ties between them are resolved by the MergeMode.
+// Per-address priorities (0xDD packets) override a source's universe
+// priority slot by slot, with 0 meaning the source does not drive the slot.
+//
+// Universe priority 0 is the lowest priority, not "off": a source at
+// priority 0 loses every slot to a source at a higher priority, but drives
+// the output when it is alone, or ties with other sources at 0 by the
+// MergeMode, unless IgnorePriorityZero is set. Slots no source drives are 0.
+type Merger struct {
+	mode MergeMode
+	// ignoreZero drops sources at universe priority 0 from the merge.
+	ignoreZero bool
+
+	mu        sync.Mutex
+	universes map[uint16]*mergeUniverse
+	updates   uint64
+	// latest maps universe numbers to *latestFrame for lock-free reads.
+	latest    sync.Map
+	onPreempt func(Preemption)
+	bus       *EventBus
+	// trims maps source CIDs to the level table of their SetTrim.
+	trims map[uuid.UUID]*[256]byte
+}
+
+type mergeUniverse struct {
+	sources map[uuid.UUID]*mergeSource
+	output  Universe
+	// held freezes output while sources continue to be tracked.
+	held bool
+	// composites maps the first slot of each composite value to its width.
+	composites map[int]int
+	// ltp, if not nil, marks the slots merged LTP whatever the mode.
+	ltp *[512]bool
+	// srcs is reused by merge to list the sources with slots.
+	srcs []*mergeSource
+}
+
+// mergeSource is the latest data from one source on one universe.
+type mergeSource struct {
+	name       string
+	priority   uint8
+	slots      [512]byte
+	hasSlots   bool
+	priorities *[512]byte
+	// order increases with every update and decides LTP ties.
+	order uint64
+}
+
+// NewMerger returns an empty Merger using mode.
+func NewMerger(mode MergeMode) *Merger {
+	return &Merger{
+		mode:      mode,
+		universes: make(map[uint16]*mergeUniverse),
+	}
+}
+
+// Update records f as the latest data from its source and returns the new
+// merged output for f's universe. Frames with the stream terminated option
+// remove the source.
+//
+// Only dimmer data (0x00) and per-address priority (0xDD) frames take part in
+// the merge. Frames with any other START code are ignored: they do not change
+// the output, and a source that sends only such frames does not become a
+// merge source. Use a StartCodeMux to route those frames to the application.
+func (m *Merger) Update(f DataFrame) Universe {
+	output, preempted, fn, bus := m.update(f)
+	for _, p := range preempted {
+		if fn != nil {
+			fn(p)
+		}
+		bus.publish(p)
+	}
+	return output
+}
+
+// update is Update without the preemption notifications, which it returns
+// along with the callback and bus to deliver them to.
+func (m *Merger) update(f DataFrame) (Universe, []Preemption, func(Preemption), *EventBus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mu := m.universe(f.Universe.Number)
+	if f.Options&flpStreamTerminateFlag[0] != 0 {
+		delete(mu.sources, f.CID)
+		m.remerge(mu)
+		return mu.output, nil, nil, nil
+	}
+	if f.StartCode != NullStartCode && f.StartCode != PriorityStartCode {
+		return mu.output, nil, nil, nil
+	}
+
+	var before map[uuid.UUID]bool
+	tracking := m.onPreempt != nil || m.bus.wants(EventPreemption)
+	if tracking {
+		before = mu.leaders()
+	}
+
+	src := mu.sources[f.CID]
+	if src == nil {
+		src = &mergeSource{}
+		mu.sources[f.CID] = src
+	}
+	m.updates++
+	src.order = m.updates
+	src.name = f.SourceName
+	src.priority = f.Priority
+	if f.StartCode == PriorityStartCode {
+		src.priorities = f.Universe.Priorities
+	} else {
+		src.slots = f.Universe.Slots
+		src.hasSlots = true
+		if trim := m.trims[f.CID]; trim != nil {
+			for i, v := range src.slots {
+				src.slots[i] = trim[v]
+			}
+		}
+	}
+	m.remerge(mu)
+	if !tracking {
+		return mu.output, nil, nil, nil
+	}
+	return mu.output, mu.preemptions(before, f.CID), m.onPreempt, m.bus
+}
+
+// universe returns the state of universe number, creating it if needed.
+func (m *Merger) universe(number uint16) *mergeUniverse {
+	mu := m.universes[number]
+	if mu == nil {
+		mu = &mergeUniverse{sources: make(map[uuid.UUID]*mergeSource)}
+		mu.output.Number = number
+		m.universes[number] = mu
+	}
+	return mu
+}
+
+// remerge recomputes and publishes mu's output unless it is held.
+func (m *Merger) remerge(mu *mergeUniverse) {
+	if mu.held {
+		return
+	}
+	mu.merge(m.mode, m.ignoreZero)
+	m.publish(mu)
+}
+
+// IgnorePriorityZero sets whether sources at universe priority 0 are left
+// out of the merge, so that a universe sent only at priority 0 outputs 0 on
+// every slot. Sources sending per-address priorities are merged by those
+// regardless. Priority 0 sources are merged by default.
+func (m *Merger) IgnorePriorityZero(ignore bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ignoreZero == ignore {
+		return
+	}
+	m.ignoreZero = ignore
+	for _, mu := range m.universes {
+		m.remerge(mu)
+	}
+}
+
+// HoldOutput freezes the merged output of universe at its current value.
+// Sources keep being tracked, so that ReleaseOutput can return to the live
+// merge, but Update and Remove report the held output until then.
+func (m *Merger) HoldOutput(universe uint16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.universe(universe).held = true
+}
+
+// ReleaseOutput ends a HoldOutput and returns the live merged output.
+func (m *Merger) ReleaseOutput(universe uint16) Universe {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu := m.universe(universe)
+	mu.held = false
+	m.remerge(mu)
+	return mu.output
+}
+
+// Remove drops the source cid from universe, for example after it has timed
+// out, and returns the new merged output.
+func (m *Merger) Remove(universe uint16, cid uuid.UUID) Universe {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu := m.universes[universe]
+	if mu == nil {
+		return Universe{Number: universe}
+	}
+	delete(mu.sources, cid)
+	m.remerge(mu)
+	return mu.output
+}
+
+// Output returns the current merged output for universe and whether any
+// data has been merged for it.
+func (m *Merger) Output(universe uint16) (Universe, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu := m.universes[universe]
+	if mu == nil {
+		return Universe{Number: universe}, false
+	}
+	return mu.output, true
+}
+
+// DeclareComposite makes the width slots of universe starting at index
+// channel one logical value, such as a 16-bit coarse/fine pair or an RGB
+// triple, so that the whole value comes from a single source. Under HTP the
+// values are compared as big-endian numbers, so the first slot is the most
+// significant; per-address priority is taken from the first slot.
+// Declarations may not overlap.
+func (m *Merger) DeclareComposite(universe uint16, channel, width int) error {
+	if width < 2 || channel < 0 || width > 512-channel {
+		return errorf(ErrChannelOutOfRange, "Unable to declare composite at channel %d width %d (out of bounds)", channel, width)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu := m.universe(universe)
+	for first, w := range mu.composites {
+		if channel < first+w && first < channel+width {
+			return errorf(ErrOverlap, "Cannot declare composite at channel %d: overlaps composite at channel %d", channel, first)
+		}
+	}
+	if mu.composites == nil {
+		mu.composites = make(map[int]int)
+	}
+	mu.composites[channel] = width
+	m.remerge(mu)
+	return nil
+}
+
+// SetTrim scales every level the source cid sends, on every universe, by
+// scale, between 0 and 1, before it is merged, so that for example a
+// visiting console can be limited to 80%. Each slot is scaled on its own,
+// attribute and composite channels included. The trim applies from the
+// source's next frame; a scale of 1 removes it.
+func (m *Merger) SetTrim(cid uuid.UUID, scale float64) error {
+	if !(scale >= 0 && scale <= 1) {
+		return errorf(ErrInvalidArgument, "Invalid trim %v (out of bounds)", scale)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if scale == 1 {
+		delete(m.trims, cid)
+		return nil
+	}
+	trim := new([256]byte)
+	for i := range trim {
+		trim[i] = byte(math.Round(float64(i) * scale))
+	}
+	if m.trims == nil {
+		m.trims = make(map[uuid.UUID]*[256]byte)
+	}
+	m.trims[cid] = trim
+	return nil
+}
+
+// DeclareLTP merges the width slots of universe starting at index channel
+// LTP, taking the level from the source that sent most recently, even under
+// HTP. This suits attribute channels such as pan, tilt or a colour wheel,
+// whose highest value means nothing. A composite value is merged as its
+// first slot is.
+func (m *Merger) DeclareLTP(universe uint16, channel, width int) error {
+	if width < 1 || channel < 0 || width > 512-channel {
+		return errorf(ErrChannelOutOfRange, "Unable to declare LTP channels at channel %d width %d (out of bounds)", channel, width)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mu := m.universe(universe)
+	if mu.ltp == nil {
+		mu.ltp = new([512]bool)
+	}
+	for i := channel; i < channel+width; i++ {
+		mu.ltp[i] = true
+	}
+	m.remerge(mu)
+	return nil
+}
+
+// merge recomputes mu.output from its sources, leaving out those at
+// universe priority 0 without per-address priorities if ignoreZero is set.
+func (mu *mergeUniverse) merge(mode MergeMode, ignoreZero bool) {
+	srcs := mu.srcs[:0]
+	uniform := true
+	for _, src := range mu.sources {
+		if !src.hasSlots || ignoreZero && src.priority == 0 && src.priorities == nil {
+			continue
+		}
+		srcs = append(srcs, src)
+		if src.priorities != nil {
+			uniform = false
+		}
+	}
+	mu.srcs = srcs
+	if mode == HTP && uniform && len(mu.composites) == 0 && mu.ltp == nil {
+		mu.mergeHTP(srcs)
+		return
+	}
+	mu.mergeSlots(mode, srcs)
+}
+
+// mergeHTP is merge for HTP when no source sends per-address priorities and
+// no composites or LTP channels are declared. Every slot is then the highest level among
+// the sources at the top universe priority, which may be 0, computed eight
+// slots at a time.
+func (mu *mergeUniverse) mergeHTP(srcs []*mergeSource) {
+	var best uint8
+	for _, src := range srcs {
+		if src.priority > best {
+			best = src.priority
+		}
+	}
+	var out [64]uint64
+	for _, src := range srcs {
+		if src.priority != best {
+			continue
+		}
+		for i := range out {
+			out[i] = maxBytes(out[i], binary.LittleEndian.Uint64(src.slots[i*8:]))
+		}
+	}
+	for i, w := range out {
+		binary.LittleEndian.PutUint64(mu.output.Slots[i*8:], w)
+	}
+}
+
+const (
+	lowBits  = 0x7f7f7f7f7f7f7f7f
+	highBits = 0x8080808080808080
+)
+
+// maxBytes returns the bytewise maximum of x and y, each taken as eight
+// unsigned bytes.
+func maxBytes(x, y uint64) uint64 {
+	// t has a byte's high bit set where x's low seven bits are >= y's. The
+	// subtraction cannot borrow across bytes.
+	t := (x | highBits) - (y & lowBits)
+	// ge has a byte's high bit set where x >= y.
+	ge := (x&^y | ^(x^y)&t) & highBits
+	mask := (ge >> 7) * 0xff
+	return x&mask | y&^mask
+}
+
+// mergeSlots is merge for the general case, resolving each slot, or
+// composite, separately, by mode or, on declared LTP channels, by LTP. A per-address priority of 0 leaves the source out
+// of that slot; a universe priority of 0 does not.
+func (mu *mergeUniverse) mergeSlots(mode MergeMode, srcs []*mergeSource) {
+	for i := 0; i < len(mu.output.Slots); {
+		width := 1
+		if w, ok := mu.composites[i]; ok {
+			width = w
+		}
+		slotMode := mode
+		if mu.ltp != nil && mu.ltp[i] {
+			slotMode = LTP
+		}
+		var winner *mergeSource
+		var best uint8
+		for _, src := range srcs {
+			p := src.priority
+			if src.priorities != nil {
+				if p = src.priorities[i]; p == 0 {
+					continue
+				}
+			}
+			switch {
+			case winner == nil || p > best:
+				winner, best = src, p
+			case p < best:
+			case slotMode == HTP && higher(src.slots[i:i+width], winner.slots[i:i+width]):
+				winner = src
+			case slotMode == LTP && src.order > winner.order:
+				winner = src
+			}
+		}
+		if winner == nil {
+			for j := i; j < i+width; j++ {
+				mu.output.Slots[j] = 0
+			}
+		} else {
+			copy(mu.output.Slots[i:i+width], winner.slots[i:i+width])
+		}
+		i += width
+	}
+}
+
+// higher reports whether the value a is greater than b, comparing
+// composites as big-endian numbers.
+func higher(a, b []byte) bool {
+	if len(a) == 1 {
+		return a[0] > b[0]
+	}
+	return bytes.Compare(a, b) > 0
+}
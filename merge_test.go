@@ -0,0 +1,179 @@
+package e131
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+func TestMaxBytes(t *testing.T) {
+	f := func(x, y uint64) bool {
+		got := maxBytes(x, y)
+		for i := 0; i < 64; i += 8 {
+			a, b, m := byte(x>>i), byte(y>>i), byte(got>>i)
+			if m != a && m != b || m < a || m < b {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// randomSources returns between 0 and 5 sources with slots, with a few
+// distinct priorities and levels so that ties are common.
+func randomSources(r *rand.Rand) []*mergeSource {
+	srcs := make([]*mergeSource, r.Intn(6))
+	for i := range srcs {
+		src := &mergeSource{priority: uint8(r.Intn(3) * 50), hasSlots: true, order: uint64(i)}
+		for j := range src.slots {
+			src.slots[j] = byte(r.Intn(4) * 85)
+		}
+		srcs[i] = src
+	}
+	return srcs
+}
+
+func TestMergeHTPMatchesSlots(t *testing.T) {
+	f := func(seed int64) bool {
+		srcs := randomSources(rand.New(rand.NewSource(seed)))
+		var fast, slow mergeUniverse
+		fast.mergeHTP(srcs)
+		slow.mergeSlots(HTP, srcs)
+		return fast.output == slow.output
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestMergePriorityZero checks that universe priority 0 is the lowest
+// priority on both merge paths: it drives the output alone and loses to any
+// higher priority.
+func TestMergePriorityZero(t *testing.T) {
+	zero := &mergeSource{priority: 0, hasSlots: true}
+	one := &mergeSource{priority: 1, hasSlots: true, order: 1}
+	zero.slots[0], one.slots[0] = 0xff, 0x10
+	paths := []struct {
+		name  string
+		merge func(*mergeUniverse, []*mergeSource)
+	}{
+		{"HTP", (*mergeUniverse).mergeHTP},
+		{"slots/HTP", func(mu *mergeUniverse, srcs []*mergeSource) { mu.mergeSlots(HTP, srcs) }},
+		{"slots/LTP", func(mu *mergeUniverse, srcs []*mergeSource) { mu.mergeSlots(LTP, srcs) }},
+	}
+	for _, p := range paths {
+		var mu mergeUniverse
+		p.merge(&mu, []*mergeSource{zero})
+		if got := mu.output.Slots[0]; got != 0xff {
+			t.Errorf("%s: priority 0 alone merged to %#02x, want ff", p.name, got)
+		}
+		p.merge(&mu, []*mergeSource{zero, one})
+		if got := mu.output.Slots[0]; got != 0x10 {
+			t.Errorf("%s: priority 0 against 1 merged to %#02x, want 10", p.name, got)
+		}
+	}
+}
+
+func TestIgnorePriorityZero(t *testing.T) {
+	m := NewMerger(HTP)
+	frames := sourceFrames(2)
+	frames[0].Priority = 0
+	if u := m.Update(frames[0]); u.Slots[1] != frames[0].Universe.Slots[1] {
+		t.Fatalf("priority 0 source not merged by default")
+	}
+	m.IgnorePriorityZero(true)
+	if u, _ := m.Output(1); u.Slots != (Universe{}).Slots {
+		t.Errorf("ignored priority 0 source still drives the output")
+	}
+
+	frames[1].Priority = 0
+	frames[1].StartCode = PriorityStartCode
+	frames[1].Universe.Priorities = new([512]byte)
+	frames[1].Universe.Priorities[1] = 100
+	m.Update(frames[1])
+	frames[1].StartCode = NullStartCode
+	u := m.Update(frames[1])
+	if u.Slots[1] != frames[1].Universe.Slots[1] || u.Slots[2] != 0 {
+		t.Errorf("source with per-address priorities merged to % x, want only slot 1 from it", u.Slots[:3])
+	}
+}
+
+func TestMergeComposite(t *testing.T) {
+	m := NewMerger(HTP)
+	if err := m.DeclareComposite(1, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	frames := sourceFrames(2)
+	frames[0].Universe.Slots[0], frames[0].Universe.Slots[1] = 0x10, 0xff
+	frames[1].Universe.Slots[0], frames[1].Universe.Slots[1] = 0x11, 0x00
+	m.Update(frames[0])
+	u := m.Update(frames[1])
+	if got := u.Slots[:2]; !bytes.Equal(got, []byte{0x11, 0x00}) {
+		t.Errorf("composite merged to % x, want 11 00", got)
+	}
+}
+
+func TestDeclareLTP(t *testing.T) {
+	m := NewMerger(HTP)
+	if err := m.DeclareLTP(1, 510, 3); err == nil {
+		t.Error("LTP channels past the end of the universe accepted")
+	}
+	if err := m.DeclareLTP(1, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	frames := sourceFrames(2)
+	for i := 0; i < 3; i++ {
+		frames[0].Universe.Slots[i], frames[1].Universe.Slots[i] = 0xff, 0x10
+	}
+	m.Update(frames[0])
+	u := m.Update(frames[1])
+	if got := u.Slots[:3]; !bytes.Equal(got, []byte{0x10, 0x10, 0xff}) {
+		t.Errorf("merged to % x, want the latest level on the LTP channels and the highest after", got)
+	}
+}
+
+func TestSetTrim(t *testing.T) {
+	m := NewMerger(HTP)
+	frames := sourceFrames(2)
+	if err := m.SetTrim(frames[0].CID, 1.5); err == nil {
+		t.Error("trim above 1 accepted")
+	}
+	if err := m.SetTrim(frames[0].CID, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	frames[0].Universe.Slots[0], frames[1].Universe.Slots[0] = 200, 120
+	m.Update(frames[0])
+	if u := m.Update(frames[1]); u.Slots[0] != 120 {
+		t.Errorf("merged to %d, want 120 over the trimmed 100", u.Slots[0])
+	}
+	m.SetTrim(frames[0].CID, 1)
+	if u := m.Update(frames[0]); u.Slots[0] != 200 {
+		t.Errorf("merged to %d after removing the trim, want 200", u.Slots[0])
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	for _, n := range []int{1, 4, 16} {
+		frames := sourceFrames(n)
+		for _, mode := range []struct {
+			name string
+			mode MergeMode
+		}{{"HTP", HTP}, {"LTP", LTP}} {
+			b.Run(fmt.Sprintf("%s/sources=%d", mode.name, n), func(b *testing.B) {
+				m := NewMerger(mode.mode)
+				for _, f := range frames {
+					m.Update(f)
+				}
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					m.Update(frames[i%n])
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package e131
+
+import (
+	"net"
+	"syscall"
+)
+
+// setMulticastInterface makes conn send multicast out of ifi.
+func setMulticastInterface(conn *net.UDPConn, ifi *net.Interface, ipv6 bool) error {
+	var ip4 [4]byte
+	if !ipv6 {
+		ip, err := interfaceIPv4(ifi)
+		if err != nil {
+			return err
+		}
+		copy(ip4[:], ip)
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		if ipv6 {
+			serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, ifi.Index)
+		} else {
+			serr = syscall.SetsockoptInet4Addr(int(fd), syscall.IPPROTO_IP, syscall.IP_MULTICAST_IF, ip4)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+// joinGroup adds the multicast group to conn's memberships on ifi, or on the
+// system's default interface if ifi is nil.
+func joinGroup(conn *net.UDPConn, ifi *net.Interface, group net.IP, ipv6 bool) error {
+	return setMembership(conn, ifi, group, ipv6, true)
+}
+
+// leaveGroup drops the multicast group from conn's memberships.
+func leaveGroup(conn *net.UDPConn, ifi *net.Interface, group net.IP, ipv6 bool) error {
+	return setMembership(conn, ifi, group, ipv6, false)
+}
+
+func setMembership(conn *net.UDPConn, ifi *net.Interface, group net.IP, ipv6, join bool) error {
+	var mreq4 syscall.IPMreq
+	var mreq6 syscall.IPv6Mreq
+	if ipv6 {
+		copy(mreq6.Multiaddr[:], group.To16())
+		if ifi != nil {
+			mreq6.Interface = uint32(ifi.Index)
+		}
+	} else {
+		copy(mreq4.Multiaddr[:], group.To4())
+		if ifi != nil {
+			ip, err := interfaceIPv4(ifi)
+			if err != nil {
+				return err
+			}
+			copy(mreq4.Interface[:], ip)
+		}
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		switch {
+		case ipv6 && join:
+			serr = syscall.SetsockoptIPv6Mreq(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_JOIN_GROUP, &mreq6)
+		case ipv6:
+			serr = syscall.SetsockoptIPv6Mreq(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_LEAVE_GROUP, &mreq6)
+		case join:
+			serr = syscall.SetsockoptIPMreq(int(fd), syscall.IPPROTO_IP, syscall.IP_ADD_MEMBERSHIP, &mreq4)
+		default:
+			serr = syscall.SetsockoptIPMreq(int(fd), syscall.IPPROTO_IP, syscall.IP_DROP_MEMBERSHIP, &mreq4)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
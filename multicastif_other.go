@@ -0,0 +1,23 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package e131
+
+import (
+	"net"
+)
+
+// setMulticastInterface is not supported on this platform.
+func setMulticastInterface(conn *net.UDPConn, ifi *net.Interface, ipv6 bool) error {
+	return errorf(ErrUnsupported, "Cannot select multicast interface on this platform")
+}
+
+// joinGroup is not supported on this platform; each universe gets its own
+// socket instead.
+func joinGroup(conn *net.UDPConn, ifi *net.Interface, group net.IP, ipv6 bool) error {
+	return errorf(ErrUnsupported, "Cannot join multicast group on this platform")
+}
+
+// leaveGroup is not supported on this platform.
+func leaveGroup(conn *net.UDPConn, ifi *net.Interface, group net.IP, ipv6 bool) error {
+	return errorf(ErrUnsupported, "Cannot leave multicast group on this platform")
+}
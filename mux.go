@@ -0,0 +1,42 @@
+package e131
+
+import (
+	"sync"
+)
+
+// StartCodeMux dispatches data frames to handlers by START code. Its Dispatch
+// method can be used as a Receiver handler, so that dimmer data (0x00) and
+// per-address priorities (0xDD) go to a Merger while alternate START codes
+// such as RDM (0xCC) or text packets reach the application separately.
+type StartCodeMux struct {
+	mu       sync.RWMutex
+	handlers map[byte]func(DataFrame)
+}
+
+// NewStartCodeMux returns a StartCodeMux with no handlers.
+func NewStartCodeMux() *StartCodeMux {
+	return &StartCodeMux{handlers: make(map[byte]func(DataFrame))}
+}
+
+// Handle registers fn for frames with startCode, replacing any previous
+// handler. A nil fn removes the handler.
+func (m *StartCodeMux) Handle(startCode byte, fn func(DataFrame)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fn == nil {
+		delete(m.handlers, startCode)
+		return
+	}
+	m.handlers[startCode] = fn
+}
+
+// Dispatch passes f to the handler for its START code. Frames with no
+// handler are dropped.
+func (m *StartCodeMux) Dispatch(f DataFrame) {
+	m.mu.RLock()
+	fn := m.handlers[f.StartCode]
+	m.mu.RUnlock()
+	if fn != nil {
+		fn(f)
+	}
+}
@@ -0,0 +1,60 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"testing"
+)
+
+// TestMixedStartCodes feeds a Merger through a StartCodeMux with sources that
+// mix dimmer (0x00), per-address priority (0xDD) and RDM (0xCC) frames.
+func TestMixedStartCodes(t *testing.T) {
+	m := NewMerger(HTP)
+	var rdm []DataFrame
+	mux := NewStartCodeMux()
+	mux.Handle(NullStartCode, func(f DataFrame) { m.Update(f) })
+	mux.Handle(PriorityStartCode, func(f DataFrame) { m.Update(f) })
+	mux.Handle(0xcc, func(f DataFrame) { rdm = append(rdm, f) })
+
+	a, b, c := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	frame := func(cid uuid.UUID, priority, startCode byte, slots ...byte) DataFrame {
+		f := DataFrame{CID: cid, Priority: priority, StartCode: startCode}
+		f.Universe.Number = 1
+		if startCode == PriorityStartCode {
+			f.Universe.Priorities = new([512]byte)
+			copy(f.Universe.Priorities[:], slots)
+		} else {
+			copy(f.Universe.Slots[:], slots)
+		}
+		return f
+	}
+
+	// a drives slot 0 at priority 100 and gives up slot 1; c drives both at
+	// its universe priority of 50; b sends only RDM, which must not merge, and
+	// START codes without a handler are dropped.
+	mux.Dispatch(frame(a, 100, NullStartCode, 100, 50))
+	mux.Dispatch(frame(a, 100, PriorityStartCode, 100, 0))
+	mux.Dispatch(frame(c, 50, NullStartCode, 200, 80))
+	mux.Dispatch(frame(b, 200, 0xcc, 255, 255))
+	mux.Dispatch(frame(a, 100, 0xcc, 255, 255))
+	mux.Dispatch(frame(uuid.Nil, 100, 0x17, 255, 255))
+
+	u, ok := m.Output(1)
+	if !ok {
+		t.Fatal("no merged output")
+	}
+	if u.Slots[0] != 100 || u.Slots[1] != 80 {
+		t.Errorf("merged slots %d %d, want 100 80", u.Slots[0], u.Slots[1])
+	}
+	if n := len(m.universes[1].sources); n != 2 {
+		t.Errorf("merge has %d sources, want 2", n)
+	}
+	if len(rdm) != 2 || rdm[0].CID != b || rdm[1].CID != a {
+		t.Errorf("RDM handler got %d frames", len(rdm))
+	}
+
+	// A merger fed directly ignores alternate START codes in the same way.
+	before := m.Update(frame(a, 100, 0xcc, 0, 0))
+	if before != u {
+		t.Error("0xCC frame changed the merged output")
+	}
+}
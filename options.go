@@ -0,0 +1,55 @@
+package e131
+
+import (
+	"strings"
+)
+
+// Options is the options field of an E1.31 data packet. Convert it to a byte
+// to pass it to Send and the packet builders, or from DataFrame.Options to
+// test the received bits.
+type Options byte
+
+// Option bits of the data packet options field.
+const (
+	// Preview marks data intended for visualizers, not live output.
+	Preview Options = 0x80
+	// StreamTerminated tells receivers the source has stopped sending the
+	// universe.
+	StreamTerminated Options = 0x40
+	// ForceSync tells synchronized receivers to act on data even when they
+	// stop receiving synchronization packets.
+	ForceSync Options = 0x20
+)
+
+// Preview reports whether the Preview bit is set.
+func (o Options) Preview() bool {
+	return o&Preview != 0
+}
+
+// StreamTerminated reports whether the StreamTerminated bit is set.
+func (o Options) StreamTerminated() bool {
+	return o&StreamTerminated != 0
+}
+
+// ForceSync reports whether the ForceSync bit is set.
+func (o Options) ForceSync() bool {
+	return o&ForceSync != 0
+}
+
+// String lists the bits that are set, for example "Preview|ForceSync".
+func (o Options) String() string {
+	var names []string
+	if o.Preview() {
+		names = append(names, "Preview")
+	}
+	if o.StreamTerminated() {
+		names = append(names, "StreamTerminated")
+	}
+	if o.ForceSync() {
+		names = append(names, "ForceSync")
+	}
+	if len(names) == 0 {
+		return "0"
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,313 @@
+package e131
+
+import (
+	"bytes"
+	"encoding/binary"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Sizes of the fixed portions of each packet type.
+const (
+	rootLayerSize     = 38
+	dataPacketMinSize = 126 // through the DMX start code
+	syncPacketSize    = 49
+	discPacketMinSize = 120 // through the last page field
+)
+
+// PacketType identifies the kind of an E1.31 packet.
+type PacketType int
+
+// Packet types returned by Classify.
+const (
+	PacketUnknown PacketType = iota
+	PacketData
+	PacketSync
+	PacketDiscovery
+)
+
+// Classify reports the kind of packet in b from its root and framing layer
+// vectors, without otherwise validating it. It lets a receiver sharing one
+// socket between data and discovery traffic pick the right parser.
+func Classify(b []byte) PacketType {
+	if len(b) < 44 {
+		return PacketUnknown
+	}
+	rootVector, flpVector := b[18:22], b[40:44]
+	switch {
+	case bytes.Equal(rootVector, rlpVectorRootE131Data):
+		return PacketData
+	case bytes.Equal(rootVector, rlpVectorRootE131Extended) &&
+		bytes.Equal(flpVector, flpVectorE131ExtendedSync):
+		return PacketSync
+	case bytes.Equal(rootVector, rlpVectorRootE131Extended) &&
+		bytes.Equal(flpVector, flpVectorE131ExtendedDisc):
+		return PacketDiscovery
+	}
+	return PacketUnknown
+}
+
+// destination returns the universe a data or synchronization packet is
+// addressed to, read straight from b without validating it, so that packets
+// for other universes can be dropped cheaply.
+func destination(b []byte) (uint16, bool) {
+	switch Classify(b) {
+	case PacketData:
+		if len(b) >= 115 {
+			return binary.BigEndian.Uint16(b[113:115]), true
+		}
+	case PacketSync:
+		if len(b) >= 47 {
+			return binary.BigEndian.Uint16(b[45:47]), true
+		}
+	}
+	return 0, false
+}
+
+// DataFrame is a decoded E1.31 data packet.
+type DataFrame struct {
+	CID        uuid.UUID
+	SourceName string
+	Priority   uint8
+	SyncAddr   uint16
+	Sequence   uint8
+	Options    byte
+	StartCode  byte
+	// SlotCount is the number of slots the packet carried after the START
+	// code, 0-512: the DMP property value count minus one. A short final
+	// packet of a universe carries fewer than 512.
+	SlotCount int
+	// Universe holds the universe number and the slots that followed the
+	// start code. Slots from SlotCount on are zero. For the 0xDD start code
+	// the slots are per-address priorities and are stored in
+	// Universe.Priorities instead.
+	Universe Universe
+}
+
+// Payload returns the slots f carried after its START code: SlotCount bytes
+// of Universe.Priorities for the 0xDD START code, and of Universe.Slots for
+// any other.
+func (f *DataFrame) Payload() []byte {
+	if f.StartCode == PriorityStartCode && f.Universe.Priorities != nil {
+		return f.Universe.Priorities[:f.SlotCount]
+	}
+	return f.Universe.Slots[:f.SlotCount]
+}
+
+// SyncFrame is a decoded E1.31 synchronization packet.
+type SyncFrame struct {
+	CID      uuid.UUID
+	Sequence uint8
+	SyncAddr uint16
+}
+
+// DiscoveryFrame is one decoded page of an E1.31 universe discovery packet.
+type DiscoveryFrame struct {
+	CID        uuid.UUID
+	SourceName string
+	Page       uint8
+	LastPage   uint8
+	Universes  []uint16
+}
+
+// parseRootLayer checks the root layer of b and returns its vector and CID.
+func parseRootLayer(b []byte) ([]byte, uuid.UUID, error) {
+	if len(b) < rootLayerSize {
+		return nil, uuid.Nil, errorf(ErrShortPacket, "Packet too short for root layer (%d bytes)", len(b))
+	}
+	if !bytes.Equal(b[0:2], rlpPreambleSize) {
+		return nil, uuid.Nil, errorf(ErrBadHeader, "Invalid root layer preamble size % x", b[0:2])
+	}
+	if !bytes.Equal(b[2:4], rlpPostambleSize) {
+		return nil, uuid.Nil, errorf(ErrBadHeader, "Invalid root layer post-amble size % x", b[2:4])
+	}
+	if !bytes.Equal(b[4:16], rlpAcnPacketIdentifier) {
+		return nil, uuid.Nil, errorf(ErrBadHeader, "Invalid ACN packet identifier")
+	}
+	cid, err := uuid.FromBytes(b[22:38])
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	return b[18:22], cid, nil
+}
+
+// Validate checks that b is a well-formed E1.31 data, synchronization or
+// universe discovery packet: the root layer preamble, post-amble and ACN
+// packet identifier, the vectors of every layer, and that each layer's
+// flags and length field matches the size of the datagram. The parsers
+// validate every packet this way.
+func Validate(b []byte) error {
+	_, err := validate(b)
+	return err
+}
+
+// validate checks b as Validate does and returns its type.
+func validate(b []byte) (PacketType, error) {
+	vector, _, err := parseRootLayer(b)
+	if err != nil {
+		return PacketUnknown, err
+	}
+	if err := checkPDULength(b, 16, "root layer"); err != nil {
+		return PacketUnknown, err
+	}
+	if len(b) < 44 {
+		return PacketUnknown, errorf(ErrShortPacket, "Packet too short for framing layer (%d bytes)", len(b))
+	}
+	if err := checkPDULength(b, 38, "framing layer"); err != nil {
+		return PacketUnknown, err
+	}
+	switch {
+	case bytes.Equal(vector, rlpVectorRootE131Data):
+		return PacketData, validateData(b)
+	case !bytes.Equal(vector, rlpVectorRootE131Extended):
+		return PacketUnknown, errorf(ErrBadVector, "Unknown root vector % x", vector)
+	case bytes.Equal(b[40:44], flpVectorE131ExtendedSync):
+		return PacketSync, validateSync(b)
+	case bytes.Equal(b[40:44], flpVectorE131ExtendedDisc):
+		return PacketDiscovery, validateDiscovery(b)
+	}
+	return PacketUnknown, errorf(ErrBadVector, "Unknown extended framing vector % x", b[40:44])
+}
+
+// checkPDULength returns an error unless the flags and length field at
+// offset has the flags 0x7 and gives the length of the rest of b.
+func checkPDULength(b []byte, offset int, layer string) error {
+	v := binary.BigEndian.Uint16(b[offset:])
+	if v>>12 != 0x7 {
+		return errorf(ErrBadLength, "Invalid %s flags %#x", layer, v>>12)
+	}
+	if want := len(b) - offset; int(v&0x0fff) != want {
+		return errorf(ErrBadLength, "Invalid %s length %d (datagram has %d bytes)", layer, v&0x0fff, want)
+	}
+	return nil
+}
+
+// validateData checks the framing and DMP layers of the data packet b.
+func validateData(b []byte) error {
+	if len(b) < dataPacketMinSize {
+		return errorf(ErrShortPacket, "Data packet too short (%d bytes)", len(b))
+	}
+	if !bytes.Equal(b[40:44], flpVectorE131DataPacket) {
+		return errorf(ErrBadVector, "Invalid data packet framing vector % x", b[40:44])
+	}
+	if err := checkPDULength(b, 115, "DMP layer"); err != nil {
+		return err
+	}
+	if b[117] != dmpVectorDmpSetProperty[0] {
+		return errorf(ErrBadVector, "Invalid DMP vector %#02x", b[117])
+	}
+	if b[118] != dmpAddressTypeDataType[0] {
+		return errorf(ErrBadDMP, "Invalid DMP address type & data type %#02x", b[118])
+	}
+	if !bytes.Equal(b[119:121], dmpFirstPropertyAddress) {
+		return errorf(ErrBadDMP, "Invalid DMP first property address % x", b[119:121])
+	}
+	if !bytes.Equal(b[121:123], dmpAddressIncrement) {
+		return errorf(ErrBadDMP, "Invalid DMP address increment % x", b[121:123])
+	}
+	count := int(binary.BigEndian.Uint16(b[123:125]))
+	if count < 1 || count > 513 {
+		return errorf(ErrBadDMP, "Invalid DMP property value count %d", count)
+	}
+	if len(b) != 125+count {
+		return errorf(ErrBadLength, "Data packet has %d bytes for %d property values", len(b), count)
+	}
+	return checkUniverse(binary.BigEndian.Uint16(b[113:115]))
+}
+
+// validateSync checks the framing layer of the synchronization packet b.
+func validateSync(b []byte) error {
+	if len(b) != syncPacketSize {
+		return errorf(ErrBadLength, "Sync packet has %d bytes, want %d", len(b), syncPacketSize)
+	}
+	return nil
+}
+
+// validateDiscovery checks the framing and universe discovery layers of the
+// discovery packet b.
+func validateDiscovery(b []byte) error {
+	if len(b) < discPacketMinSize {
+		return errorf(ErrShortPacket, "Discovery packet too short (%d bytes)", len(b))
+	}
+	if err := checkPDULength(b, 112, "universe discovery layer"); err != nil {
+		return err
+	}
+	if !bytes.Equal(b[114:118], udlVectorUnivDiscUnivList) {
+		return errorf(ErrBadVector, "Invalid universe discovery vector % x", b[114:118])
+	}
+	if n := len(b) - discPacketMinSize; n%2 != 0 {
+		return errorf(ErrBadLength, "Universe discovery list has odd length %d", n)
+	}
+	return nil
+}
+
+// ParseDataPacket decodes an E1.31 data packet after checking it with
+// Validate.
+func ParseDataPacket(b []byte) (DataFrame, error) {
+	var f DataFrame
+	t, err := validate(b)
+	if err != nil {
+		return f, err
+	}
+	if t != PacketData {
+		return f, errorf(ErrWrongPacketType, "Not an e131 data packet (root vector % x)", b[18:22])
+	}
+
+	count := int(binary.BigEndian.Uint16(b[123:125]))
+	f.CID, _ = uuid.FromBytes(b[22:38])
+	f.SourceName = nullTerminated(b[44:108])
+	f.Priority = b[108]
+	f.SyncAddr = binary.BigEndian.Uint16(b[109:111])
+	f.Sequence = b[111]
+	f.Options = b[112]
+	f.StartCode = b[125]
+	f.SlotCount = count - 1
+	f.Universe.Number = binary.BigEndian.Uint16(b[113:115])
+	if f.StartCode == PriorityStartCode {
+		f.Universe.Priorities = new([512]byte)
+		copy(f.Universe.Priorities[:], b[126:125+count])
+	} else {
+		copy(f.Universe.Slots[:], b[126:125+count])
+	}
+	return f, nil
+}
+
+// ParseSyncPacket decodes an E1.31 synchronization packet after checking it
+// with Validate.
+func ParseSyncPacket(b []byte) (SyncFrame, error) {
+	var f SyncFrame
+	t, err := validate(b)
+	if err != nil {
+		return f, err
+	}
+	if t != PacketSync {
+		return f, errorf(ErrWrongPacketType, "Not an e131 sync packet (vectors % x, % x)", b[18:22], b[40:44])
+	}
+
+	f.CID, _ = uuid.FromBytes(b[22:38])
+	f.Sequence = b[44]
+	f.SyncAddr = binary.BigEndian.Uint16(b[45:47])
+	return f, nil
+}
+
+// ParseDiscoveryPacket decodes one page of an E1.31 universe discovery
+// packet after checking it with Validate.
+func ParseDiscoveryPacket(b []byte) (DiscoveryFrame, error) {
+	var f DiscoveryFrame
+	t, err := validate(b)
+	if err != nil {
+		return f, err
+	}
+	if t != PacketDiscovery {
+		return f, errorf(ErrWrongPacketType, "Not an e131 discovery packet (vectors % x, % x)", b[18:22], b[40:44])
+	}
+
+	f.CID, _ = uuid.FromBytes(b[22:38])
+	f.SourceName = nullTerminated(b[44:108])
+	f.Page = b[118]
+	f.LastPage = b[119]
+	list := b[discPacketMinSize:]
+	for i := 0; i < len(list); i += 2 {
+		f.Universes = append(f.Universes, binary.BigEndian.Uint16(list[i:]))
+	}
+	return f, nil
+}
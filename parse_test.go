@@ -0,0 +1,242 @@
+package e131
+
+import (
+	"bytes"
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"testing"
+	"testing/quick"
+)
+
+// testUniverse maps n into the valid universe range.
+func testUniverse(n uint16) uint16 {
+	return n%MaxUniverse + MinUniverse
+}
+
+// testSourceName returns the source name a packet can carry made from b:
+// at most 63 bytes, without nulls.
+func testSourceName(b []byte) string {
+	b = bytes.ReplaceAll(b, []byte{0}, nil)
+	if len(b) > 63 {
+		b = b[:63]
+	}
+	return string(b)
+}
+
+func TestDataPacketRoundTrip(t *testing.T) {
+	f := func(cid [16]byte, name []byte, priority, seq, options uint8, syncAddr, number uint16, slots [512]byte) bool {
+		cfg := Config{CID: uuid.UUID(cid), SourceName: testSourceName(name), Priority: priority}
+		u := Universe{Number: testUniverse(number), Slots: slots}
+		b, err := dataPacket(nil, &cfg, syncAddr, seq, options, u)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		got, err := ParseDataPacket(b)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return got == DataFrame{
+			CID:        cfg.CID,
+			SourceName: cfg.SourceName,
+			Priority:   priority,
+			SyncAddr:   syncAddr,
+			Sequence:   seq,
+			Options:    options,
+			StartCode:  NullStartCode,
+			SlotCount:  512,
+			Universe:   u,
+		}
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPriorityPacketRoundTrip(t *testing.T) {
+	f := func(cid [16]byte, seq uint8, number uint16, priorities [512]byte) bool {
+		cfg := Config{CID: uuid.UUID(cid), SourceName: "test", Priority: 100}
+		u := Universe{Number: testUniverse(number), Priorities: &priorities}
+		b, err := priorityPacket(nil, &cfg, NoSync, seq, 0, u)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		got, err := ParseDataPacket(b)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return got.StartCode == PriorityStartCode && got.CID == cfg.CID && got.Sequence == seq &&
+			got.Universe.Number == u.Number && got.Universe.Priorities != nil &&
+			*got.Universe.Priorities == priorities && got.Universe.Slots == [512]byte{}
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSyncPacketRoundTrip(t *testing.T) {
+	f := func(cid [16]byte, seq uint8, syncAddr uint16) bool {
+		b, err := syncPacket(nil, &Config{CID: uuid.UUID(cid)}, syncAddr, seq)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		got, err := ParseSyncPacket(b)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		return got == SyncFrame{CID: uuid.UUID(cid), Sequence: seq, SyncAddr: syncAddr}
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiscoveryPacketRoundTrip(t *testing.T) {
+	f := func(cid [16]byte, name []byte, page, lastPage uint8, numbers []uint16) bool {
+		if len(numbers) > maxDiscoveryUniverses {
+			numbers = numbers[:maxDiscoveryUniverses]
+		}
+		universes := make([]Universe, len(numbers))
+		for i, n := range numbers {
+			numbers[i] = testUniverse(n)
+			universes[i].Number = numbers[i]
+		}
+		cfg := Config{CID: uuid.UUID(cid), SourceName: testSourceName(name)}
+		b, err := discPacket(&cfg, page, lastPage, universes)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		got, err := ParseDiscoveryPacket(b)
+		if err != nil {
+			t.Log(err)
+			return false
+		}
+		if len(got.Universes) != len(numbers) {
+			return false
+		}
+		for i := range numbers {
+			if got.Universes[i] != numbers[i] {
+				return false
+			}
+		}
+		return got.CID == cfg.CID && got.SourceName == cfg.SourceName &&
+			got.Page == page && got.LastPage == lastPage
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// testPackets returns a valid data, sync and discovery packet.
+func testPackets(t *testing.T) map[string][]byte {
+	cfg := Config{CID: uuid.NewV4(), SourceName: "test", Priority: 100}
+	data, err := dataPacket(nil, &cfg, NoSync, 1, 0, Universe{Number: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync, err := syncPacket(nil, &cfg, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disc, err := discPacket(&cfg, 0, 0, []Universe{{Number: 1}, {Number: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{"data": data, "sync": sync, "discovery": disc}
+}
+
+// checkedBytes lists, per packet type, the offsets of the fields Validate
+// checks exactly: any change to one of them must be rejected.
+var checkedBytes = map[string][]int{
+	"data": concat(span(0, 22), span(38, 44), span(115, 125)),
+	"sync": concat(span(0, 22), span(38, 44)),
+	// The discovery page fields at 118 and 119 are not checked.
+	"discovery": concat(span(0, 22), span(38, 44), span(112, 118)),
+}
+
+// span returns the offsets from i up to j.
+func span(i, j int) []int {
+	var s []int
+	for ; i < j; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+// concat joins spans.
+func concat(spans ...[]int) []int {
+	var s []int
+	for _, sp := range spans {
+		s = append(s, sp...)
+	}
+	return s
+}
+
+func TestValidateRejectsMutations(t *testing.T) {
+	for name, packet := range testPackets(t) {
+		offsets := checkedBytes[name]
+		f := func(i uint, x byte) bool {
+			if x == 0 {
+				return true
+			}
+			b := append([]byte(nil), packet...)
+			b[offsets[i%uint(len(offsets))]] ^= x
+			return Validate(b) != nil
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateRejectsTruncation(t *testing.T) {
+	for name, packet := range testPackets(t) {
+		f := func(n uint) bool {
+			return Validate(packet[:n%uint(len(packet))]) != nil
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	packets := testPackets(t)
+	for _, tc := range []struct {
+		name   string
+		packet string
+		offset int
+		value  byte
+		want   error
+	}{
+		{"preamble", "data", 1, 0x11, ErrBadHeader},
+		{"post-amble", "data", 3, 0x01, ErrBadHeader},
+		{"packet identifier", "sync", 4, 'a', ErrBadHeader},
+		{"root flags", "data", 16, 0x62, ErrBadLength},
+		{"root length", "discovery", 17, 0x00, ErrBadLength},
+		{"root vector", "data", 21, 0x05, ErrBadVector},
+		{"framing length", "sync", 39, 0x00, ErrBadLength},
+		{"data framing vector", "data", 43, 0x01, ErrBadVector},
+		{"extended framing vector", "sync", 43, 0x03, ErrBadVector},
+		{"DMP length", "data", 116, 0x00, ErrBadLength},
+		{"DMP vector", "data", 117, 0x01, ErrBadVector},
+		{"DMP address type", "data", 118, 0xa0, ErrBadDMP},
+		{"DMP first address", "data", 120, 0x01, ErrBadDMP},
+		{"DMP increment", "data", 122, 0x02, ErrBadDMP},
+		{"DMP count", "data", 124, 0x00, ErrBadLength},
+		{"universe", "data", 114, 0x00, ErrUniverseOutOfRange},
+		{"discovery vector", "discovery", 117, 0x02, ErrBadVector},
+	} {
+		b := append([]byte(nil), packets[tc.packet]...)
+		b[tc.offset] = tc.value
+		if err := Validate(b); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net"
+)
+
+// SourcePin is a source allowed to send a pinned universe: a CID and,
+// optionally, the address its packets must come from.
+type SourcePin struct {
+	CID uuid.UUID
+	// IP, if not nil, is the only address accepted for CID.
+	IP net.IP
+}
+
+// PinMismatch reports a data packet for a pinned universe from a source,
+// or an address, that is not pinned. sACN is not authenticated, so this is
+// how a Receiver notices a misconfigured or spoofed source.
+type PinMismatch struct {
+	Universe   uint16
+	CID        uuid.UUID
+	SourceName string
+	// Addr is where the packet came from, or nil if the transport does not
+	// report it.
+	Addr net.Addr
+}
+
+// Pin accepts frames for universe only from the sources in pins, dropping
+// the others and publishing a PinMismatch for each on the EventBus. A pin
+// with an IP also rejects packets whose address is not reported. Pinning
+// no sources lifts the pin.
+func (r *Receiver) Pin(universe uint16, pins ...SourcePin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(pins) == 0 {
+		delete(r.pins, universe)
+		return
+	}
+	if r.pins == nil {
+		r.pins = make(map[uint16][]SourcePin)
+	}
+	r.pins[universe] = append([]SourcePin(nil), pins...)
+}
+
+// pinned reports whether f, received from addr, may be delivered.
+func (r *Receiver) pinned(f DataFrame, addr net.Addr) bool {
+	r.mu.Lock()
+	pins, ok := r.pins[f.Universe.Number]
+	r.mu.Unlock()
+	if !ok {
+		return true
+	}
+	var ip net.IP
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		ip = udp.IP
+	}
+	for _, p := range pins {
+		if uuid.Equal(p.CID, f.CID) && (p.IP == nil || p.IP.Equal(ip)) {
+			return true
+		}
+	}
+	return false
+}
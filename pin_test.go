@@ -0,0 +1,56 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	frames := make(chan DataFrame, 4)
+	r, c := testReceiver(t, func(f DataFrame) { frames <- f })
+	mismatches := make(chan PinMismatch, 4)
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventPinMismatch}, func(e Event) { mismatches <- e.(PinMismatch) })
+	r.SetEventBus(bus)
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+	console, spoof := testSource(), testSource()
+	r.Pin(1, SourcePin{CID: console.CID, IP: sourceAddr.IP})
+
+	other := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 99), Port: Port}
+	c.deliver(levelPacket(t, spoof, 1, 0, 1), sourceAddr)
+	c.deliver(levelPacket(t, console, 1, 0, 2), other)
+	c.deliver(levelPacket(t, console, 1, 1, 3), sourceAddr)
+
+	select {
+	case f := <-frames:
+		if f.Universe.Slots[0] != 3 {
+			t.Errorf("delivered level %d, want only the pinned source's 3", f.Universe.Slots[0])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pinned source not delivered")
+	}
+	for _, want := range []struct {
+		cid  uuid.UUID
+		addr *net.UDPAddr
+	}{{spoof.CID, sourceAddr}, {console.CID, other}} {
+		m := <-mismatches
+		if m.CID != want.cid || m.Addr != want.addr || m.Universe != 1 {
+			t.Errorf("got %+v, want a mismatch from %v at %v", m, want.cid, want.addr)
+		}
+	}
+
+	r.Pin(1)
+	c.deliver(levelPacket(t, spoof, 1, 1, 4), sourceAddr)
+	select {
+	case f := <-frames:
+		if f.CID != spoof.CID {
+			t.Errorf("delivered %v, want the formerly rejected source", f.CID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("source not delivered after the pin was lifted")
+	}
+}
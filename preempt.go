@@ -0,0 +1,179 @@
+package e131
+
+import (
+	"bytes"
+	"encoding/json"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Preemption reports that a source driving a universe's merged output has
+// been overridden by a source with a higher universe priority.
+type Preemption struct {
+	Universe   uint16
+	Loser      uuid.UUID
+	LoserName  string
+	Winner     uuid.UUID
+	WinnerName string
+	// Priority is the universe priority of the winner.
+	Priority uint8
+}
+
+// OnPreempt sets fn to be called whenever Update makes a source lose the
+// output of a universe to one with a higher universe priority. fn is called
+// after the merge, outside the Merger's lock, from the goroutine calling
+// Update. Per-address priorities are not considered.
+//
+// Deprecated: Subscribe to EventPreemption on an EventBus given to
+// SetEventBus, which carries every kind of notification.
+func (m *Merger) OnPreempt(fn func(Preemption)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPreempt = fn
+}
+
+// SetEventBus publishes the Merger's preemptions on bus, as OnPreempt
+// describes. Preemptions are only tracked while something subscribes to
+// them. nil stops publishing.
+func (m *Merger) SetEventBus(bus *EventBus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bus = bus
+}
+
+// leaders returns the sources of mu at the highest universe priority, which
+// are the ones driving its output.
+func (mu *mergeUniverse) leaders() map[uuid.UUID]bool {
+	var best uint8
+	leaders := make(map[uuid.UUID]bool)
+	for cid, src := range mu.sources {
+		if !src.hasSlots {
+			continue
+		}
+		if src.priority > best {
+			best = src.priority
+			leaders = make(map[uuid.UUID]bool)
+		}
+		if src.priority == best {
+			leaders[cid] = true
+		}
+	}
+	return leaders
+}
+
+// preemptions returns the sources in before that lost the lead of mu to
+// winner.
+func (mu *mergeUniverse) preemptions(before map[uuid.UUID]bool, winner uuid.UUID) []Preemption {
+	after := mu.leaders()
+	w := mu.sources[winner]
+	if !after[winner] {
+		return nil
+	}
+	var events []Preemption
+	for cid := range before {
+		loser, ok := mu.sources[cid]
+		if !ok || after[cid] {
+			continue
+		}
+		events = append(events, Preemption{
+			Universe:   mu.output.Number,
+			Loser:      cid,
+			LoserName:  loser.name,
+			Winner:     winner,
+			WinnerName: w.name,
+			Priority:   w.priority,
+		})
+	}
+	return events
+}
+
+// LogPreemptions returns an OnPreempt function that writes each preemption
+// to l.
+func LogPreemptions(l *log.Logger) func(Preemption) {
+	return func(p Preemption) {
+		l.Printf("universe %d: %q (%s) preempted by %q (%s) at priority %d",
+			p.Universe, p.LoserName, p.Loser, p.WinnerName, p.Winner, p.Priority)
+	}
+}
+
+// postQueueSize is how many preemptions PostPreemptions holds while earlier
+// ones are being posted; more are dropped.
+const postQueueSize = 64
+
+// postTimeout bounds each post made by PostPreemptions.
+const postTimeout = 5 * time.Second
+
+// PostPreemptions returns an OnPreempt function that POSTs each preemption
+// as JSON to url using client, or http.DefaultClient if client is nil. Posts
+// are made one at a time in the background, so that merging is not delayed:
+// up to 64 preemptions wait their turn and further ones are dropped. Each
+// post times out after 5 seconds, or client.Timeout if that is set; client
+// itself is not changed. Failures are ignored.
+func PostPreemptions(client *http.Client, url string) func(Preemption) {
+	return newPreemptionPoster(client, url).post
+}
+
+// preemptionPoster queues preemptions for a single worker to post.
+type preemptionPoster struct {
+	client *http.Client
+	url    string
+
+	mu    sync.Mutex
+	queue [][]byte
+	// running is set while a worker is posting the queue.
+	running bool
+}
+
+func newPreemptionPoster(client *http.Client, url string) *preemptionPoster {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if client.Timeout == 0 {
+		c := *client
+		c.Timeout = postTimeout
+		client = &c
+	}
+	return &preemptionPoster{client: client, url: url}
+}
+
+// post queues p, starting a worker if none is running, or drops it if the
+// queue is full.
+func (q *preemptionPoster) post(p Preemption) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) >= postQueueSize {
+		return
+	}
+	q.queue = append(q.queue, body)
+	if !q.running {
+		q.running = true
+		go q.run()
+	}
+}
+
+// run posts the queue until it is empty.
+func (q *preemptionPoster) run() {
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		body := q.queue[0]
+		q.queue = append(q.queue[:0], q.queue[1:]...)
+		q.mu.Unlock()
+
+		resp, err := q.client.Post(q.url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
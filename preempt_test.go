@@ -0,0 +1,142 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sourceFrames returns one dimmer frame for universe 1 from each of n
+// sources at priority 100, with differing levels.
+func sourceFrames(n int) []DataFrame {
+	frames := make([]DataFrame, n)
+	for i := range frames {
+		f := &frames[i]
+		f.CID = uuid.NewV4()
+		f.Priority = 100
+		f.Universe.Number = 1
+		for j := range f.Universe.Slots {
+			f.Universe.Slots[j] = byte(i*37 + j)
+		}
+	}
+	return frames
+}
+
+func TestOnPreempt(t *testing.T) {
+	m := NewMerger(HTP)
+	var got []Preemption
+	m.OnPreempt(func(p Preemption) { got = append(got, p) })
+
+	frames := sourceFrames(2)
+	frames[0].SourceName = "low"
+	frames[1].SourceName = "high"
+	frames[1].Priority = 150
+	m.Update(frames[0])
+	m.Update(frames[1])
+	if len(got) != 1 {
+		t.Fatalf("got %d preemptions, want 1", len(got))
+	}
+	if p := got[0]; p.LoserName != "low" || p.WinnerName != "high" || p.Priority != 150 {
+		t.Errorf("got %+v", p)
+	}
+}
+
+// BenchmarkMergerUpdate measures the cost of preemption tracking: without an
+// OnPreempt callback or a subscriber to EventPreemption the Merger skips it
+// entirely. BenchmarkObservers covers the Receiver's observers.
+func BenchmarkMergerUpdate(b *testing.B) {
+	frames := sourceFrames(4)
+	for _, tc := range []struct {
+		name string
+		fn   func(Preemption)
+	}{
+		{"off", nil},
+		{"OnPreempt", func(Preemption) {}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			m := NewMerger(HTP)
+			m.OnPreempt(tc.fn)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Update(frames[i%len(frames)])
+			}
+		})
+	}
+}
+
+// waitIdle waits up to timeout for q's worker to finish, and reports whether
+// it did.
+func waitIdle(q *preemptionPoster, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		running := q.running
+		q.mu.Unlock()
+		if !running {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestPostPreemptionsBounded(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight, received := 0, 0, 0
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		received++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	q := newPreemptionPoster(nil, srv.URL)
+	if q.client == http.DefaultClient || q.client.Timeout != postTimeout {
+		t.Errorf("client timeout %v, want %v on a copy of the default client", q.client.Timeout, postTimeout)
+	}
+	for i := 0; i < 3*postQueueSize; i++ {
+		q.post(Preemption{Universe: 1})
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if !waitIdle(q, 5*time.Second) {
+		t.Fatal("queue not drained")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInFlight != 1 {
+		t.Errorf("%d posts in flight at once, want 1", maxInFlight)
+	}
+	if received > postQueueSize+1 {
+		t.Errorf("%d preemptions posted, want at most %d with the rest dropped", received, postQueueSize+1)
+	}
+}
+
+func TestPostPreemptionsTimeout(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	q := newPreemptionPoster(&http.Client{Timeout: 20 * time.Millisecond}, srv.URL)
+	for i := 0; i < 3; i++ {
+		q.post(Preemption{Universe: 1})
+	}
+	if !waitIdle(q, 2*time.Second) {
+		t.Fatal("posts to an unresponsive server did not time out")
+	}
+}
@@ -0,0 +1,67 @@
+package e131
+
+import (
+	"time"
+)
+
+// Profile is a set of transmit defaults that makes a Sender behave like a
+// class of real-world source, so that receivers and the Merger can be tested
+// against realistic peers.
+type Profile struct {
+	Name string
+	// Priority is the universe priority the source sends with.
+	Priority uint8
+	// KeepAlive is how often unchanged data is retransmitted; see
+	// Config.KeepAlive.
+	KeepAlive time.Duration
+	// SyncAddr is the synchronization universe data is sent to, or NoSync.
+	SyncAddr uint16
+	// ForceSync is whether synchronized data carries the ForceSync option.
+	ForceSync bool
+	// Discovery is whether the source advertises its universes.
+	Discovery bool
+	// FrameRate is the rate, in frames per second, at which the source
+	// sends while its data is changing. The Sender does not pace sends, so
+	// it is for the caller's send loop.
+	FrameRate float64
+}
+
+// Profiles of common kinds of source.
+var (
+	// ConsoleProfile is a lighting console: full rate while levels change
+	// and a refresh about once a second otherwise.
+	ConsoleProfile = Profile{Name: "console", Priority: 100, KeepAlive: time.Second, Discovery: true, FrameRate: 44}
+	// BackupConsoleProfile is a tracking backup that only takes over when
+	// the main console's higher priority data goes away.
+	BackupConsoleProfile = Profile{Name: "backup console", Priority: 90, KeepAlive: time.Second, Discovery: true, FrameRate: 44}
+	// MediaServerProfile is a pixel-mapping media server that streams every
+	// frame continuously and drives synchronized output, on MaxUniverse by
+	// default; apply WithSync after it to choose another universe.
+	MediaServerProfile = Profile{Name: "media server", Priority: 100, SyncAddr: MaxUniverse, ForceSync: true, Discovery: true, FrameRate: 60}
+	// SlowRefreshProfile refreshes unchanged data at half the
+	// DataLossTimeout, the slowest rate that still leaves receivers a safe
+	// margin, and like older sources does not advertise its universes.
+	SlowRefreshProfile = Profile{Name: "slow refresh", Priority: 100, KeepAlive: DataLossTimeout / 2, FrameRate: 30}
+)
+
+// WithProfile applies the priority, keep-alive, synchronization and
+// discovery settings of p.
+func WithProfile(p Profile) Option {
+	return func(c *Config) error {
+		if err := checkPriority(int(p.Priority)); err != nil {
+			return err
+		}
+		if err := checkKeepAlive(p.KeepAlive); err != nil {
+			return err
+		}
+		if err := checkSyncAddr(p.SyncAddr); err != nil {
+			return err
+		}
+		c.Priority = p.Priority
+		c.KeepAlive = p.KeepAlive
+		c.SyncAddr = p.SyncAddr
+		c.ForceSync = p.ForceSync
+		c.Discovery = p.Discovery
+		return nil
+	}
+}
@@ -0,0 +1,22 @@
+package e131
+
+import "testing"
+
+func TestProfiles(t *testing.T) {
+	for _, p := range []Profile{ConsoleProfile, BackupConsoleProfile, MediaServerProfile, SlowRefreshProfile} {
+		cfg := Config{SourceName: "test"}
+		if err := WithProfile(p)(&cfg); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+			continue
+		}
+		if err := cfg.check(); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+		if cfg.KeepAlive > DataLossTimeout/2 {
+			t.Errorf("%s: KeepAlive %v leaves receivers less than half the DataLossTimeout", p.Name, cfg.KeepAlive)
+		}
+		if cfg.ForceSync && !cfg.Synchronized() {
+			t.Errorf("%s: ForceSync without a synchronization universe", p.Name)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package e131
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxPacketSize is large enough for any E1.31 packet.
+const maxPacketSize = 1144
+
+// maxGroupsPerSocket is how many universes share one multicast socket. It is
+// Linux's default limit on group memberships per socket.
+const maxGroupsPerSocket = 20
+
+// Receiver listens for E1.31 data packets for the universes it has joined and
+// hands each decoded frame to its handler. By default universes are joined
+// on multicast sockets shared by up to maxGroupsPerSocket universes each, or
+// one per universe where a socket cannot join further groups;
+// NewReceiverConn instead reads every universe from one caller-supplied
+// transport.
+type Receiver struct {
+	handler func(DataFrame)
+	// conn is the caller-supplied transport, or nil when universes are
+	// joined on the Receiver's own multicast sockets.
+	conn PacketConn
+	// ipv6 joins the IPv6 multicast groups instead of the IPv4 ones.
+	ipv6 bool
+
+	mu sync.Mutex
+	// ifi is the interface that multicast sockets join on, or nil for
+	// the system default.
+	ifi *net.Interface
+	// joined maps each joined universe to the socket it is read from, or to
+	// nil when every universe is read from conn.
+	joined map[uint16]*shard
+	shards []*shard
+	// handled holds the universes joined with Join, whose frames go to
+	// handler; joined may also hold universes only subscribed to.
+	handled map[uint16]bool
+	subs    map[uint16][]*Subscription
+	sources map[sourceKey]*sourceState
+	lost    func(SourceLost)
+	bus     *EventBus
+	// ignorePreview drops frames with the Preview option.
+	ignorePreview bool
+	// quiet turns the observers off; see SetObservers.
+	quiet atomic.Bool
+	// syncs is nil unless synchronization is on.
+	syncs map[uint16]*syncState
+	// pins holds the sources allowed on pinned universes.
+	pins map[uint16][]SourcePin
+	// startCodes counts the frames of each universe by START code, and
+	// unexpected holds the unexpected START codes already reported.
+	startCodes map[uint16]*startCodeStats
+	unexpected map[unexpectedKey]bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// shard is a multicast socket receiving the universes mapped to it in
+// Receiver.joined.
+type shard struct {
+	conn *net.UDPConn
+	// groups is the number of universes joined on conn.
+	groups int
+	// full stops further universes joining conn after a join failed.
+	full bool
+}
+
+// NewReceiver returns a Receiver that calls handler for every data packet
+// received on a joined universe. The handler is called from one goroutine per
+// socket, so it must be safe for concurrent use when several universes are
+// joined.
+func NewReceiver(handler func(DataFrame)) *Receiver {
+	return newReceiver(nil, handler)
+}
+
+// NewReceiver6 is like NewReceiver but joins the IPv6 multicast group of each
+// universe.
+func NewReceiver6(handler func(DataFrame)) *Receiver {
+	r := newReceiver(nil, handler)
+	r.ipv6 = true
+	return r
+}
+
+// NewReceiverConn returns a Receiver that reads packets from conn and calls
+// handler for those on joined universes. Join and Leave only change which
+// universes are delivered. Close closes conn.
+func NewReceiverConn(conn PacketConn, handler func(DataFrame)) *Receiver {
+	r := newReceiver(conn, handler)
+	r.wg.Add(1)
+	go r.listen(nil, conn)
+	return r
+}
+
+func newReceiver(conn PacketConn, handler func(DataFrame)) *Receiver {
+	r := &Receiver{
+		handler:    handler,
+		conn:       conn,
+		joined:     make(map[uint16]*shard),
+		handled:    make(map[uint16]bool),
+		subs:       make(map[uint16][]*Subscription),
+		sources:    make(map[sourceKey]*sourceState),
+		startCodes: make(map[uint16]*startCodeStats),
+		unexpected: make(map[unexpectedKey]bool),
+		done:       make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.watchLoss()
+	return r
+}
+
+// SetInterface selects the network interface that later calls to Join use
+// for their multicast sockets. nil lets the system choose. It has no effect
+// on a Receiver using a caller-supplied transport.
+func (r *Receiver) SetInterface(ifi *net.Interface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ifi = ifi
+}
+
+// SetEventBus publishes the Receiver's notifications on bus: sources found
+// and lost, synchronization lost, and parse and socket errors. nil stops
+// publishing.
+func (r *Receiver) SetEventBus(bus *EventBus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bus = bus
+}
+
+// eventBus returns the bus set with SetEventBus.
+func (r *Receiver) eventBus() *EventBus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bus
+}
+
+// IgnorePreview makes the Receiver drop frames marked as preview data, as a
+// receiver driving live output should. They are neither delivered nor
+// counted as source activity. By default preview frames are delivered.
+func (r *Receiver) IgnorePreview(ignore bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ignorePreview = ignore
+}
+
+// SetObservers turns the observers on the receive path on or off. They are
+// source tracking, behind Sources, StreamState, OnSourceLost and the
+// SourceFound, SourceLost and StreamTransition events, and the START code
+// statistics. Turning them off, for the highest throughput, forgets the
+// sources tracked so far and stops sources that time out being removed
+// from SubscribeMerged output. Frames are still filtered, pinned,
+// synchronized and delivered. Observers are on by default.
+func (r *Receiver) SetObservers(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quiet.Store(!on)
+	if !on {
+		r.sources = make(map[sourceKey]*sourceState)
+		r.unexpected = make(map[unexpectedKey]bool)
+	}
+}
+
+// observe passes f to the observers, unless they are off.
+func (r *Receiver) observe(f DataFrame) {
+	if r.quiet.Load() {
+		return
+	}
+	r.countStartCode(f)
+	r.track(f)
+}
+
+// Join subscribes to universe and starts delivering its frames.
+func (r *Receiver) Join(universe uint16) error {
+	if err := checkUniverse(universe); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.joinLocked(universe); err != nil {
+		return err
+	}
+	r.handled[universe] = true
+	return nil
+}
+
+// joinLocked starts receiving universe, with r.mu held.
+func (r *Receiver) joinLocked(universe uint16) error {
+	if r.joined == nil {
+		return errorf(ErrClosed, "Cannot join universe on closed Receiver")
+	}
+	if _, ok := r.joined[universe]; ok {
+		return nil
+	}
+	if r.conn != nil {
+		r.joined[universe] = nil
+		return nil
+	}
+
+	group := multicastAddr(universe, r.ipv6)
+	for _, sh := range r.shards {
+		if sh.full || sh.groups >= maxGroupsPerSocket {
+			continue
+		}
+		if err := joinGroup(sh.conn, r.ifi, group.IP, r.ipv6); err != nil {
+			// The platform or the system's membership limit refused
+			// the group; give the universe a socket of its own.
+			sh.full = true
+			break
+		}
+		sh.groups++
+		r.joined[universe] = sh
+		return nil
+	}
+
+	conn, err := net.ListenMulticastUDP(network(r.ipv6), r.ifi, group)
+	if err != nil {
+		return err
+	}
+	sh := &shard{conn: conn, groups: 1}
+	r.shards = append(r.shards, sh)
+	r.joined[universe] = sh
+	r.wg.Add(1)
+	go r.listen(sh, conn)
+	return nil
+}
+
+// Leave stops delivering frames for universe to the handler and, unless the
+// universe still has subscriptions, stops receiving it, leaving its multicast
+// group and closing its socket once no universe uses it.
+func (r *Receiver) Leave(universe uint16) error {
+	r.mu.Lock()
+	delete(r.handled, universe)
+	if len(r.subs[universe]) > 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	return r.leaveLocked(universe)
+}
+
+// leaveLocked stops receiving universe. It is called with r.mu held and
+// releases it.
+func (r *Receiver) leaveLocked(universe uint16) error {
+	sh, ok := r.joined[universe]
+	delete(r.joined, universe)
+	for k := range r.sources {
+		if k.universe == universe {
+			delete(r.sources, k)
+		}
+	}
+	for _, st := range r.syncs {
+		delete(st.pending, universe)
+	}
+	if !ok || sh == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	sh.groups--
+	if sh.groups > 0 {
+		defer r.mu.Unlock()
+		return leaveGroup(sh.conn, r.ifi, multicastAddr(universe, r.ipv6).IP, r.ipv6)
+	}
+	for i, s := range r.shards {
+		if s == sh {
+			r.shards = append(r.shards[:i], r.shards[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+	return sh.conn.Close()
+}
+
+// Close leaves every joined universe, closes the transport and waits for the
+// handler to return from any in-flight calls.
+func (r *Receiver) Close() error {
+	r.mu.Lock()
+	if r.joined == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	shards := r.shards
+	r.joined, r.shards = nil, nil
+	r.mu.Unlock()
+	close(r.done)
+
+	var err error
+	closeConn := func(conn PacketConn) {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for _, sh := range shards {
+		closeConn(sh.conn)
+	}
+	if r.conn != nil {
+		closeConn(r.conn)
+	}
+	r.wg.Wait()
+	return err
+}
+
+// receives reports whether universe is joined and read from sh, which is nil
+// for a shared transport.
+func (r *Receiver) receives(sh *shard, universe uint16) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.joined[universe]
+	return ok && s == sh
+}
+
+// ignoringPreview reports whether preview frames are dropped.
+func (r *Receiver) ignoringPreview() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ignorePreview
+}
+
+// listen reads packets from conn until it is closed. Sockets bound to the
+// sACN port see the traffic of every group joined on the host, so packets
+// are kept only for the universes joined on sh, or on the shared transport
+// when sh is nil, and the others are dropped before parsing.
+func (r *Receiver) listen(sh *shard, conn PacketConn) {
+	defer r.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			r.eventBus().publish(SocketError{Err: err})
+			continue
+		}
+		u, ok := destination(buf[:n])
+		if !ok || !r.receives(sh, u) {
+			continue
+		}
+		if Classify(buf[:n]) == PacketSync {
+			r.handleSync(buf[:n], addr)
+			continue
+		}
+		f, err := ParseDataPacket(buf[:n])
+		if err != nil {
+			r.eventBus().publish(ParseError{Universe: u, Addr: addr, Err: err})
+			continue
+		}
+		if !r.pinned(f, addr) {
+			r.eventBus().publish(PinMismatch{Universe: u, CID: f.CID, SourceName: f.SourceName, Addr: addr})
+			continue
+		}
+		if Options(f.Options).Preview() && r.ignoringPreview() {
+			continue
+		}
+		r.observe(f)
+		if r.hold(f, time.Now()) {
+			continue
+		}
+		r.deliver(f)
+	}
+}
+
+// handleSync delivers the frames held for the synchronization packet b,
+// received from addr.
+func (r *Receiver) handleSync(b []byte, addr net.Addr) {
+	s, err := ParseSyncPacket(b)
+	if err != nil {
+		u, _ := destination(b)
+		r.eventBus().publish(ParseError{Universe: u, Addr: addr, Err: err})
+		return
+	}
+	for _, f := range r.release(s.SyncAddr, time.Now()) {
+		r.deliver(f)
+	}
+}
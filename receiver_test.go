@@ -0,0 +1,257 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// nextFrame returns the next frame from frames, failing t if none arrives
+// within a second.
+func nextFrame(t *testing.T, frames <-chan DataFrame) DataFrame {
+	t.Helper()
+	select {
+	case f := <-frames:
+		return f
+	case <-time.After(time.Second):
+		t.Fatal("frame not delivered")
+		return DataFrame{}
+	}
+}
+
+func TestDestinationFilter(t *testing.T) {
+	frames := make(chan DataFrame, 4)
+	r, c := testReceiver(t, func(f DataFrame) { frames <- f })
+	parseErrors := make(chan Event, 4)
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventParseError}, func(e Event) { parseErrors <- e })
+	r.SetEventBus(bus)
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testSource()
+	bad := levelPacket(t, cfg, 2, 0, 2)
+	bad[117] = 0
+	sync, err := cfg.SyncPacket(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.deliver([]byte("not E1.31"), sourceAddr)
+	c.deliver(levelPacket(t, cfg, 2, 0, 2), sourceAddr)
+	c.deliver(bad, sourceAddr)
+	c.deliver(sync, sourceAddr)
+	c.deliver(levelPacket(t, cfg, 1, 0, 1), sourceAddr)
+
+	if f := nextFrame(t, frames); f.Universe.Number != 1 || f.Universe.Slots[0] != 1 {
+		t.Errorf("delivered universe %d level %d, want only universe 1", f.Universe.Number, f.Universe.Slots[0])
+	}
+	if len(r.Sources(2)) != 0 {
+		t.Error("source of an unjoined universe tracked")
+	}
+	select {
+	case e := <-parseErrors:
+		t.Errorf("packet for an unjoined universe parsed: %v", e)
+	default:
+	}
+}
+
+func TestLeave(t *testing.T) {
+	frames := make(chan DataFrame, 4)
+	r, c := testReceiver(t, func(f DataFrame) { frames <- f })
+	for _, u := range []uint16{1, 2} {
+		if err := r.Join(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	subscribed := make(chan DataFrame, 4)
+	sub, err := r.Subscribe(1, func(f DataFrame) { subscribed <- f })
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testSource()
+	c.deliver(levelPacket(t, cfg, 1, 0, 1), sourceAddr)
+	nextFrame(t, frames)
+	nextFrame(t, subscribed)
+
+	if err := r.Leave(1); err != nil {
+		t.Fatal(err)
+	}
+	c.deliver(levelPacket(t, cfg, 1, 1, 2), sourceAddr)
+	c.deliver(levelPacket(t, cfg, 2, 0, 3), sourceAddr)
+	if f := nextFrame(t, subscribed); f.Universe.Slots[0] != 2 {
+		t.Errorf("subscription got level %d after Leave, want 2", f.Universe.Slots[0])
+	}
+	if f := nextFrame(t, frames); f.Universe.Number != 2 {
+		t.Errorf("handler got universe %d after leaving it", f.Universe.Number)
+	}
+
+	if err := sub.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	_, joined := r.joined[1]
+	r.mu.Unlock()
+	if joined || len(r.Sources(1)) != 0 {
+		t.Error("universe still received after its handler and subscription left")
+	}
+	if err := r.Leave(3); err != nil {
+		t.Errorf("leaving an unjoined universe: %v", err)
+	}
+	r.Close()
+	if err := r.Join(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("join after Close: %v, want ErrClosed", err)
+	}
+}
+
+// TestReceiverShards joins enough universes on the host's own multicast
+// sockets to need several, and checks how they are shared out.
+func TestReceiverShards(t *testing.T) {
+	r := NewReceiver(func(DataFrame) {})
+	defer r.Close()
+	r.SetInterface(multicastInterface(t))
+	n := 2*maxGroupsPerSocket + 1
+	for u := 1; u <= n; u++ {
+		if err := r.Join(uint16(u)); err != nil {
+			t.Skipf("cannot join multicast groups: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	shards := append([]*shard(nil), r.shards...)
+	groups := 0
+	for _, sh := range shards {
+		if sh.groups > maxGroupsPerSocket {
+			t.Errorf("socket joined %d groups, want at most %d", sh.groups, maxGroupsPerSocket)
+		}
+		groups += sh.groups
+	}
+	var first []uint16
+	for u, sh := range r.joined {
+		if sh == shards[0] {
+			first = append(first, u)
+		}
+	}
+	r.mu.Unlock()
+	if len(shards) < 3 || groups != n {
+		t.Fatalf("%d universes joined on %d sockets holding %d groups", n, len(shards), groups)
+	}
+	for _, u := range first {
+		for _, sh := range shards[1:] {
+			if r.receives(sh, u) {
+				t.Errorf("universe %d read from two sockets", u)
+			}
+		}
+	}
+
+	for _, u := range first {
+		if err := r.Leave(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.mu.Lock()
+	for _, sh := range r.shards {
+		if sh == shards[0] {
+			t.Error("socket kept after its universes left")
+		}
+	}
+	r.mu.Unlock()
+	if _, _, err := shards[0].conn.ReadFrom(make([]byte, 1)); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("socket of left universes not closed: %v", err)
+	}
+}
+
+func TestSetObservers(t *testing.T) {
+	frames := make(chan DataFrame, 4)
+	r, c := testReceiver(t, func(f DataFrame) { frames <- f })
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testSource()
+	receive := func(seq uint8) {
+		t.Helper()
+		c.deliver(levelPacket(t, cfg, 1, seq, seq), sourceAddr)
+		select {
+		case <-frames:
+		case <-time.After(time.Second):
+			t.Fatal("frame not delivered")
+		}
+	}
+
+	receive(0)
+	if len(r.Sources(1)) != 1 || len(r.StartCodes(1)) != 1 {
+		t.Fatal("frame not observed")
+	}
+	r.SetObservers(false)
+	if len(r.Sources(1)) != 0 {
+		t.Error("sources kept with observers off")
+	}
+	receive(1)
+	if len(r.Sources(1)) != 0 || r.StartCodes(1)[NullStartCode] != 1 {
+		t.Error("frame observed with observers off")
+	}
+	r.SetObservers(true)
+	receive(2)
+	if len(r.Sources(1)) != 1 || r.StartCodes(1)[NullStartCode] != 2 {
+		t.Error("frame not observed with observers back on")
+	}
+}
+
+// BenchmarkObservers measures what the Receiver's observers cost per frame,
+// and what SetObservers(false) saves.
+func BenchmarkObservers(b *testing.B) {
+	frames := sourceFrames(4)
+	for _, on := range []bool{true, false} {
+		name := "on"
+		if !on {
+			name = "off"
+		}
+		b.Run(name, func(b *testing.B) {
+			r := NewReceiverConn(newMemConn(), func(DataFrame) {})
+			defer r.Close()
+			r.SetObservers(on)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.observe(frames[i%len(frames)])
+			}
+		})
+	}
+}
+
+// BenchmarkReceive measures the receive path a frame takes from the
+// transport to the handler: destination filtering, parsing, the observers
+// and delivery.
+func BenchmarkReceive(b *testing.B) {
+	var packets [][]byte
+	for i := 0; i < 4; i++ {
+		packets = append(packets, levelPacket(b, testSource(), 1, 0, uint8(i)))
+	}
+	for _, on := range []bool{true, false} {
+		name := "observers"
+		if !on {
+			name = "quiet"
+		}
+		b.Run(name, func(b *testing.B) {
+			c := newMemConn()
+			done := make(chan struct{})
+			n := 0
+			r := NewReceiverConn(c, func(DataFrame) {
+				if n++; n == b.N {
+					close(done)
+				}
+			})
+			defer r.Close()
+			r.SetObservers(on)
+			if err := r.Join(1); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.deliver(packets[i%len(packets)], sourceAddr)
+			}
+			<-done
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package e131
+
+import (
+	"errors"
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"sync"
+	"time"
+)
+
+// Sender transmits E1.31 data packets over a UDP socket that it owns, or a
+// caller-supplied PacketConn, to the multicast group of each universe or to
+// the unicast destinations in its Config.
+type Sender struct {
+	cfg Config
+
+	mu     sync.Mutex
+	conn   PacketConn
+	claims map[uint16]*Lease
+	seq    map[uint16]uint8
+	// syncSeq holds the sequence numbers of synchronization packets.
+	syncSeq map[uint16]uint8
+	rate    rateWindow
+	// preview holds the universes sent as preview data.
+	preview map[uint16]bool
+	// created and initialized implement Config.Withhold.
+	created     time.Time
+	initialized map[uint16]bool
+	// last holds the most recent packet of each kind per universe, for
+	// keep-alive retransmission.
+	last map[lastKey]*lastSend
+	// buf is reused to encode each packet, so steady sending does not
+	// allocate a new one per frame.
+	buf []byte
+	// dests caches the destinations of each universe.
+	dests map[uint16][]*DestinationStatus
+	// intervals caches the keep-alive interval of each universe.
+	intervals map[uint16]time.Duration
+	// paused holds the universes suspended with Pause.
+	paused map[uint16]bool
+	// advertised is the universe list last advertised, and changed wakes
+	// advertise when streams start or stop.
+	advertised []uint16
+	changed    chan struct{}
+	stop       chan struct{}
+}
+
+// lastKey identifies a stream of packets kept alive: one universe and START
+// code.
+type lastKey struct {
+	universe  uint16
+	startCode byte
+}
+
+// lastSend is what is needed to retransmit a packet.
+type lastSend struct {
+	lease        *Lease
+	build        packetBuilder
+	syncAddr     uint16
+	optionsFlags byte
+	universe     Universe
+	at           time.Time
+}
+
+// NewSender opens the UDP socket used for sending as the source described by
+// cfg. Close must be called to release it. Use New to start from
+// DefaultConfig.
+func NewSender(cfg Config) (*Sender, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP(network(cfg.IPv6), nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Interface != nil {
+		if err := setMulticastInterface(conn, cfg.Interface, cfg.IPv6); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return NewSenderConn(cfg, conn)
+}
+
+// NewSenderConn returns a Sender that transmits as cfg over conn instead of
+// its own UDP socket. Close closes conn.
+func NewSenderConn(cfg Config, conn PacketConn) (*Sender, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	if uuid.Equal(cfg.CID, uuid.Nil) {
+		cfg.CID = uuid.NewV4()
+	}
+	if cfg.Unicast != nil {
+		unicast := make(map[uint16][]*net.UDPAddr, len(cfg.Unicast))
+		for u, addrs := range cfg.Unicast {
+			unicast[u] = append([]*net.UDPAddr(nil), addrs...)
+		}
+		cfg.Unicast = unicast
+	}
+	if cfg.UniverseKeepAlive != nil {
+		intervals := make(map[uint16]time.Duration, len(cfg.UniverseKeepAlive))
+		for u, d := range cfg.UniverseKeepAlive {
+			intervals[u] = d
+		}
+		cfg.UniverseKeepAlive = intervals
+	}
+	if cfg.DestinationKeepAlive != nil {
+		intervals := make(map[string]time.Duration, len(cfg.DestinationKeepAlive))
+		for a, d := range cfg.DestinationKeepAlive {
+			intervals[a] = d
+		}
+		cfg.DestinationKeepAlive = intervals
+	}
+	s := &Sender{
+		cfg:         cfg,
+		conn:        conn,
+		claims:      make(map[uint16]*Lease),
+		seq:         make(map[uint16]uint8),
+		syncSeq:     make(map[uint16]uint8),
+		preview:     make(map[uint16]bool),
+		created:     time.Now(),
+		initialized: make(map[uint16]bool),
+		last:        make(map[lastKey]*lastSend),
+		dests:       make(map[uint16][]*DestinationStatus),
+		intervals:   make(map[uint16]time.Duration),
+		paused:      make(map[uint16]bool),
+		changed:     make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+	if interval := cfg.shortestKeepAlive(); interval > 0 {
+		go s.keepAlive(interval)
+	}
+	if cfg.Discovery {
+		go s.advertise()
+	}
+	return s, nil
+}
+
+// Config returns the source identity the Sender transmits with.
+func (s *Sender) Config() Config {
+	return s.cfg
+}
+
+// Send builds a data packet for universe, as DataPacket does but with the
+// Sender's Config, and transmits it to the universe's multicast group. Each
+// universe has its own sequence number, which the Sender increments with
+// every packet. Send fails if the universe has been claimed; the owner must
+// send through its Lease instead. It also fails, without sending, if the
+// packet would exceed the Sender's Limits. While a universe is withheld,
+// see Config.Withhold, Send returns nil without transmitting.
+//
+// The packet uses the Sender's default synchronization address,
+// Config.SyncAddr; see SendSynced to choose one per packet.
+func (s *Sender) Send(optionsFlags byte, universe Universe) error {
+	return s.send(nil, dataPacket, s.cfg.SyncAddr, optionsFlags, universe)
+}
+
+// SendSynced is like Send but addresses the packet to the synchronization
+// universe syncAddr, or NoSync, regardless of the Sender's default.
+func (s *Sender) SendSynced(syncAddr uint16, optionsFlags byte, universe Universe) error {
+	if err := checkSyncAddr(syncAddr); err != nil {
+		return err
+	}
+	return s.send(nil, dataPacket, syncAddr, optionsFlags, universe)
+}
+
+// SendPriorities transmits universe.Priorities as a per-address priority
+// (0xDD) packet. It shares the universe's sequence numbers and claim with
+// Send.
+func (s *Sender) SendPriorities(optionsFlags byte, universe Universe) error {
+	return s.send(nil, priorityPacket, s.cfg.SyncAddr, optionsFlags, universe)
+}
+
+// SendStartCode transmits a data packet for universe carrying startCode
+// followed by payload, up to 512 bytes, opaquely and at its own length, so
+// that alternate START codes such as 0xCC for RDM can be tunnelled. It
+// shares the universe's sequence numbers, claim and limits with Send, but
+// the packet is not kept alive. Use Send and SendPriorities for the 0x00 and
+// 0xDD START codes.
+func (s *Sender) SendStartCode(optionsFlags byte, universe uint16, startCode byte, payload []byte) error {
+	build, err := startCodeBuilder(startCode, payload)
+	if err != nil {
+		return err
+	}
+	return s.send(nil, build, s.cfg.SyncAddr, optionsFlags, Universe{Number: universe})
+}
+
+// startCodeBuilder returns a packetBuilder for an alternate START code
+// packet carrying payload.
+func startCodeBuilder(startCode byte, payload []byte) (packetBuilder, error) {
+	if startCode == NullStartCode || startCode == PriorityStartCode {
+		return nil, errorf(ErrInvalidArgument, "Cannot send START code %#02x opaquely: use Send or SendPriorities", startCode)
+	}
+	return func(dst []byte, cfg *Config, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error) {
+		return appendStartCodePacket(dst, cfg, syncAddr, seqID, optionsFlags, startCode, universe.Number, payload)
+	}, nil
+}
+
+// SetPreview marks universe as preview data, or clears the mark. Every packet
+// sent for a marked universe has the Preview option set, whatever options
+// the caller passes.
+func (s *Sender) SetPreview(universe uint16, preview bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if preview {
+		s.preview[universe] = true
+	} else {
+		delete(s.preview, universe)
+	}
+}
+
+// MarkInitialized tells a Sender with Config.Withhold set that the
+// application has composed universe, so that it may be transmitted.
+func (s *Sender) MarkInitialized(universe uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initialized[universe] = true
+}
+
+// withheld reports whether universe must not be transmitted yet.
+func (s *Sender) withheld(universe uint16, now time.Time) bool {
+	return s.cfg.Withhold > 0 && !s.initialized[universe] && now.Sub(s.created) < s.cfg.Withhold
+}
+
+// packetBuilder appends a packet for universe to dst; see dataPacket.
+type packetBuilder func(dst []byte, cfg *Config, syncAddr uint16, seqID uint8, optionsFlags byte, universe Universe) ([]byte, error)
+
+// send transmits universe, encoded by build, on behalf of lease, which is nil
+// for unclaimed writes. Withheld universes are silently not sent.
+func (s *Sender) send(lease *Lease, build packetBuilder, syncAddr uint16, optionsFlags byte, universe Universe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sendLocked(lease, build, syncAddr, optionsFlags, universe, time.Now())
+}
+
+// sendLocked is send with s.mu held, at time now.
+func (s *Sender) sendLocked(lease *Lease, build packetBuilder, syncAddr uint16, optionsFlags byte, universe Universe, now time.Time) error {
+	if s.conn == nil {
+		return errorf(ErrClosed, "Cannot send on closed Sender")
+	}
+	if owner := s.claims[universe.Number]; owner != lease {
+		if lease != nil && owner == nil {
+			return errorf(ErrLeaseReleased, "Cannot send universe %d: lease was released", universe.Number)
+		}
+		return errorf(ErrClaimed, "Cannot send universe %d: claimed by another writer", universe.Number)
+	}
+
+	if s.withheld(universe.Number, now) {
+		return nil
+	}
+
+	seq, sent := s.seq[universe.Number]
+	if max := s.cfg.Limits.MaxUniverses; !sent && max > 0 && len(s.seq) >= max {
+		return errorf(ErrLimitExceeded, "Cannot send universe %d: limit of %d universes reached", universe.Number, max)
+	}
+
+	flags := optionsFlags
+	if s.preview[universe.Number] {
+		flags |= byte(Preview)
+	}
+	if s.cfg.ForceSync && syncAddr != NoSync {
+		flags |= byte(ForceSync)
+	}
+	data, err := build(s.buf[:0], &s.cfg, syncAddr, seq, flags, universe)
+	if err != nil {
+		return err
+	}
+	s.buf = data
+	key := lastKey{universe.Number, data[dataPacketMinSize-1]}
+	keep := key.startCode == NullStartCode || key.startCode == PriorityStartCode
+	if s.paused[universe.Number] {
+		if keep {
+			s.remember(key, lastSend{lease, build, syncAddr, optionsFlags, universe, now})
+		}
+		return nil
+	}
+	dests := s.destinations(universe.Number)
+	if err := s.rate.allow(s.cfg.Limits, now, len(dests), len(data)); err != nil {
+		return err
+	}
+	s.seq[universe.Number]++
+	streams := len(s.last)
+	if optionsFlags&flpStreamTerminateFlag[0] != 0 {
+		delete(s.last, key)
+	} else if keep {
+		s.remember(key, lastSend{lease, build, syncAddr, optionsFlags, universe, now})
+	}
+	if len(s.last) != streams {
+		s.streamsChangedLocked()
+	}
+	return s.write(data, dests, now)
+}
+
+// remember keeps l as the last packet of key, for keep-alive. The
+// per-address priorities are copied into storage reused across packets.
+func (s *Sender) remember(key lastKey, l lastSend) {
+	ls := s.last[key]
+	if ls == nil {
+		ls = &lastSend{}
+		s.last[key] = ls
+	}
+	if l.universe.Priorities != nil {
+		p := ls.universe.Priorities
+		if p == nil {
+			p = new([512]byte)
+		}
+		*p = *l.universe.Priorities
+		l.universe.Priorities = p
+	}
+	*ls = l
+}
+
+// Pause suspends sending universe, keep-alive included, without terminating
+// its stream. While paused, sends for the universe return nil without
+// transmitting and without using sequence numbers, but the last packet of
+// each START code is kept, so that after Resume receivers see the stream
+// carry on rather than a new one start. Terminate and Close still send the
+// termination sequence.
+func (s *Sender) Pause(universe uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused[universe] = true
+}
+
+// Resume ends a Pause and at once sends the last packet of each START code
+// given for universe, continuing its sequence numbers. Packets sent by a
+// lease that has since been released are dropped instead.
+func (s *Sender) Resume(universe uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused[universe] {
+		return nil
+	}
+	delete(s.paused, universe)
+	now := time.Now()
+	var errs []error
+	for _, code := range []byte{NullStartCode, PriorityStartCode} {
+		key := lastKey{universe, code}
+		l := s.last[key]
+		if l == nil {
+			continue
+		}
+		if s.claims[universe] != l.lease {
+			delete(s.last, key)
+			continue
+		}
+		errs = append(errs, s.sendLocked(l.lease, l.build, l.syncAddr, l.optionsFlags, l.universe, now))
+	}
+	return errors.Join(errs...)
+}
+
+// destinations returns where packets for universe are sent. They are
+// computed once per universe.
+func (s *Sender) destinations(universe uint16) []*DestinationStatus {
+	if dests, ok := s.dests[universe]; ok {
+		return dests
+	}
+	var dests []*DestinationStatus
+	if unicast, ok := s.cfg.Unicast[universe]; ok {
+		dests = make([]*DestinationStatus, len(unicast))
+		for i, a := range unicast {
+			dests[i] = &DestinationStatus{Addr: a}
+		}
+	} else {
+		dests = []*DestinationStatus{{Addr: multicastAddr(universe, s.cfg.IPv6)}}
+	}
+	s.dests[universe] = dests
+	return dests
+}
+
+// write sends data to each of dests at now. A failed destination does not
+// keep the packet from the others; the errors of all that failed are joined,
+// each as a *DestinationError.
+func (s *Sender) write(data []byte, dests []*DestinationStatus, now time.Time) error {
+	var errs []error
+	for _, d := range dests {
+		if _, err := s.conn.WriteTo(data, d.Addr); err != nil {
+			d.failed(err, now)
+			errs = append(errs, &DestinationError{Addr: d.Addr, Err: err})
+			continue
+		}
+		d.Sent++
+		d.ConsecutiveFailures = 0
+	}
+	return errors.Join(errs...)
+}
+
+// Sync sends a synchronization packet on syncAddr, telling receivers to act
+// on the data they have been holding for it. Synchronization packets have
+// their own sequence numbers per synchronization address.
+func (s *Sender) Sync(syncAddr uint16) error {
+	if err := checkUniverse(syncAddr); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return errorf(ErrClosed, "Cannot send on closed Sender")
+	}
+	data, err := syncPacket(s.buf[:0], &s.cfg, syncAddr, s.syncSeq[syncAddr])
+	if err != nil {
+		return err
+	}
+	s.buf = data
+	dests, now := s.destinations(syncAddr), time.Now()
+	if err := s.rate.allow(s.cfg.Limits, now, len(dests), len(data)); err != nil {
+		return err
+	}
+	s.syncSeq[syncAddr]++
+	return s.write(data, dests, now)
+}
+
+// Terminate tells receivers that the Sender has stopped sending universe by
+// sending three data packets with the stream terminated option, as E1.31
+// requires, so that they release the source at once instead of timing it
+// out. Keep-alive stops for the universe. Like Send, it fails if the
+// universe has been claimed.
+func (s *Sender) Terminate(universe uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.terminateLocked(nil, universe)
+}
+
+// terminateLocked sends the termination sequence for universe on behalf of
+// lease, with s.mu held, ending any Pause. The packets repeat the last data
+// sent.
+func (s *Sender) terminateLocked(lease *Lease, universe uint16) error {
+	delete(s.paused, universe)
+	u, syncAddr := Universe{Number: universe}, s.cfg.SyncAddr
+	if l := s.last[lastKey{universe, NullStartCode}]; l != nil {
+		u, syncAddr = l.universe, l.syncAddr
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.sendLocked(lease, dataPacket, syncAddr, flpStreamTerminateFlag[0], u, time.Now()); err != nil {
+			return err
+		}
+	}
+	delete(s.last, lastKey{universe, PriorityStartCode})
+	s.streamsChangedLocked()
+	return nil
+}
+
+// Close terminates every universe the Sender is still sending, see
+// Terminate, and releases its socket. Subsequent sends return an error.
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	var err error
+	for key := range s.last {
+		if _, ok := s.last[key]; !ok {
+			continue // already terminated with its other START code
+		}
+		if terr := s.terminateLocked(s.claims[key.universe], key.universe); terr != nil && err == nil {
+			err = terr
+		}
+	}
+	if cerr := s.conn.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	s.conn = nil
+	close(s.stop)
+	return err
+}
+
+// Lease is exclusive write ownership of one universe on a Sender.
+type Lease struct {
+	s        *Sender
+	universe uint16
+}
+
+// Claim takes ownership of universe so that only the returned Lease may send
+// it. Claiming is optional; unclaimed universes can be sent by anyone. It
+// fails if the universe is already claimed.
+func (s *Sender) Claim(universe uint16) (*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claims[universe] != nil {
+		return nil, errorf(ErrClaimed, "Cannot claim universe %d: already claimed", universe)
+	}
+	l := &Lease{s: s, universe: universe}
+	s.claims[universe] = l
+	return l, nil
+}
+
+// Send transmits universe, which must be the leased universe.
+func (l *Lease) Send(optionsFlags byte, universe Universe) error {
+	return l.send(dataPacket, l.s.cfg.SyncAddr, optionsFlags, universe)
+}
+
+// SendSynced is like Send but addresses the packet to syncAddr; see
+// Sender.SendSynced.
+func (l *Lease) SendSynced(syncAddr uint16, optionsFlags byte, universe Universe) error {
+	if err := checkSyncAddr(syncAddr); err != nil {
+		return err
+	}
+	return l.send(dataPacket, syncAddr, optionsFlags, universe)
+}
+
+// SendPriorities transmits the per-address priorities of universe, which must
+// be the leased universe.
+func (l *Lease) SendPriorities(optionsFlags byte, universe Universe) error {
+	return l.send(priorityPacket, l.s.cfg.SyncAddr, optionsFlags, universe)
+}
+
+// SendStartCode transmits an alternate START code packet for the leased
+// universe; see Sender.SendStartCode.
+func (l *Lease) SendStartCode(optionsFlags byte, startCode byte, payload []byte) error {
+	build, err := startCodeBuilder(startCode, payload)
+	if err != nil {
+		return err
+	}
+	return l.send(build, l.s.cfg.SyncAddr, optionsFlags, Universe{Number: l.universe})
+}
+
+func (l *Lease) send(build packetBuilder, syncAddr uint16, optionsFlags byte, universe Universe) error {
+	if universe.Number != l.universe {
+		return errorf(ErrWrongUniverse, "Cannot send universe %d on lease for universe %d", universe.Number, l.universe)
+	}
+	return l.s.send(l, build, syncAddr, optionsFlags, universe)
+}
+
+// Terminate sends the termination sequence for the leased universe; see
+// Sender.Terminate.
+func (l *Lease) Terminate() error {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+	if l.s.claims[l.universe] != l {
+		return errorf(ErrLeaseReleased, "Cannot terminate universe %d: lease was released", l.universe)
+	}
+	return l.s.terminateLocked(l, l.universe)
+}
+
+// Release gives up ownership, after which the lease can no longer send and
+// the universe may be claimed again.
+func (l *Lease) Release() {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+	if l.s.claims[l.universe] == l {
+		delete(l.s.claims, l.universe)
+	}
+}
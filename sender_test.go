@@ -0,0 +1,371 @@
+package e131
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// testSender returns a Sender for cfg, with discovery off, writing to a
+// memConn. It is closed when t ends.
+func testSender(t *testing.T, cfg Config) (*Sender, *memConn) {
+	t.Helper()
+	if cfg.SourceName == "" {
+		cfg.SourceName = "test"
+	}
+	cfg.Discovery = false
+	c := newMemConn()
+	s, err := NewSenderConn(cfg, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, c
+}
+
+// sentFrames decodes the data packets in packets.
+func sentFrames(t *testing.T, packets []memPacket) []DataFrame {
+	t.Helper()
+	var frames []DataFrame
+	for _, p := range packets {
+		if Classify(p.data) != PacketData {
+			continue
+		}
+		f, err := ParseDataPacket(p.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// nopConn is a PacketConn that discards everything written to it.
+type nopConn struct{}
+
+func (nopConn) ReadFrom(p []byte) (int, net.Addr, error)     { return 0, nil, net.ErrClosed }
+func (nopConn) WriteTo(p []byte, addr net.Addr) (int, error) { return len(p), nil }
+func (nopConn) Close() error                                 { return nil }
+
+// benchSender returns a Sender writing to a nopConn, closed when b ends.
+func benchSender(b *testing.B) *Sender {
+	s, err := NewSenderConn(Config{SourceName: "bench", Priority: 100}, nopConn{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+	return s
+}
+
+// BenchmarkSend measures the steady state of sending one universe, which
+// reuses the Sender's packet buffer rather than allocating one per packet.
+func BenchmarkSend(b *testing.B) {
+	s := benchSender(b)
+	u := Universe{Number: 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u.Slots[0] = byte(i)
+		if err := s.Send(0, u); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSync(b *testing.B) {
+	s := benchSender(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := s.Sync(1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	s, c := testSender(t, Config{KeepAlive: time.Second})
+	u := Universe{Number: 1}
+	u.Slots[0] = 1
+	if err := s.Send(0, u); err != nil {
+		t.Fatal(err)
+	}
+	s.Pause(1)
+	u.Slots[0] = 2
+	if err := s.Send(0, u); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keepAliveLocked(time.Now().Add(time.Minute), time.Second)
+	s.mu.Unlock()
+	if n := len(c.packets()); n != 1 {
+		t.Fatalf("%d packets sent, want none after the pause", n-1)
+	}
+
+	if err := s.Resume(1); err != nil {
+		t.Fatal(err)
+	}
+	u.Slots[0] = 3
+	if err := s.Send(0, u); err != nil {
+		t.Fatal(err)
+	}
+	frames := sentFrames(t, c.packets())
+	if len(frames) != 3 {
+		t.Fatalf("%d packets sent, want 3", len(frames))
+	}
+	for i, f := range frames {
+		if f.Sequence != uint8(i) || f.Universe.Slots[0] != uint8(i+1) {
+			t.Errorf("packet %d has sequence %d and level %d, want %d and %d", i, f.Sequence, f.Universe.Slots[0], i, i+1)
+		}
+	}
+}
+
+func TestTerminatePaused(t *testing.T) {
+	s, c := testSender(t, Config{})
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.Pause(1)
+	if err := s.Terminate(1); err != nil {
+		t.Fatal(err)
+	}
+	frames := sentFrames(t, c.packets())
+	if len(frames) != 4 || !Options(frames[3].Options).StreamTerminated() {
+		t.Errorf("paused universe not terminated: %d packets sent", len(frames))
+	}
+}
+
+func TestSendStartCode(t *testing.T) {
+	s, c := testSender(t, Config{KeepAlive: time.Second})
+	payload := []byte{0x01, 0x18, 0x02}
+	if err := s.SendStartCode(0, 1, 0xcc, payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SendStartCode(0, 1, NullStartCode, payload); err == nil {
+		t.Error("null START code sent opaquely")
+	}
+	if err := s.SendStartCode(0, 1, 0xcc, make([]byte, 513)); err == nil {
+		t.Error("513-byte payload accepted")
+	}
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keepAliveLocked(time.Now().Add(2*time.Second), time.Second)
+	s.mu.Unlock()
+
+	frames := sentFrames(t, c.packets())
+	if len(frames) != 3 {
+		t.Fatalf("%d packets sent, want 3", len(frames))
+	}
+	f := frames[0]
+	if f.StartCode != 0xcc || !bytes.Equal(f.Payload(), payload) || f.Sequence != 0 {
+		t.Errorf("sent START code %#02x, payload % x, sequence %d", f.StartCode, f.Payload(), f.Sequence)
+	}
+	if f := frames[2]; f.StartCode != NullStartCode || f.Sequence != 2 {
+		t.Errorf("kept alive START code %#02x with sequence %d, want only the level data", f.StartCode, f.Sequence)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	s, c := testSender(t, Config{})
+	l, err := s.Claim(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Claim(1); !errors.Is(err, ErrClaimed) {
+		t.Errorf("second claim: %v, want ErrClaimed", err)
+	}
+	if err := s.Send(0, Universe{Number: 1}); !errors.Is(err, ErrClaimed) {
+		t.Errorf("unclaimed send to a claimed universe: %v, want ErrClaimed", err)
+	}
+	if err := l.Send(0, Universe{Number: 2}); !errors.Is(err, ErrWrongUniverse) {
+		t.Errorf("lease sent another universe: %v, want ErrWrongUniverse", err)
+	}
+	if err := l.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Release()
+	if err := l.Send(0, Universe{Number: 1}); !errors.Is(err, ErrLeaseReleased) {
+		t.Errorf("send on released lease: %v, want ErrLeaseReleased", err)
+	}
+	if err := l.Terminate(); !errors.Is(err, ErrLeaseReleased) {
+		t.Errorf("terminate on released lease: %v, want ErrLeaseReleased", err)
+	}
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Errorf("send after release: %v", err)
+	}
+	again, err := s.Claim(1)
+	if err != nil {
+		t.Fatalf("claim after release: %v", err)
+	}
+	l.Release()
+	if err := again.Send(0, Universe{Number: 1}); err != nil {
+		t.Errorf("stale Release dropped the new claim: %v", err)
+	}
+	if n := len(sentFrames(t, c.packets())); n != 3 {
+		t.Errorf("%d packets sent, want 3", n)
+	}
+}
+
+func TestWithhold(t *testing.T) {
+	s, c := testSender(t, Config{Withhold: time.Hour})
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.packets()); n != 0 {
+		t.Fatalf("%d packets sent for an uninitialized universe", n)
+	}
+	s.MarkInitialized(1)
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	err := s.sendLocked(nil, dataPacket, NoSync, 0, Universe{Number: 2}, time.Now().Add(time.Hour))
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := sentFrames(t, c.packets())
+	if len(frames) != 2 {
+		t.Fatalf("%d packets sent, want 2", len(frames))
+	}
+	for i, f := range frames {
+		if f.Universe.Number != uint16(i+1) || f.Sequence != 0 {
+			t.Errorf("packet %d is universe %d, sequence %d; want universe %d, sequence 0", i, f.Universe.Number, f.Sequence, i+1)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s, _ := testSender(t, Config{Limits: Limits{MaxUniverses: 2}})
+	for _, u := range []uint16{1, 2, 1} {
+		if err := s.Send(0, Universe{Number: u}); err != nil {
+			t.Fatalf("universe %d: %v", u, err)
+		}
+	}
+	if err := s.Send(0, Universe{Number: 3}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("third universe: %v, want ErrLimitExceeded", err)
+	}
+
+	s, c := testSender(t, Config{Limits: Limits{MaxPacketsPerSecond: 3}})
+	now := time.Now()
+	send := func(at time.Time) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.sendLocked(nil, dataPacket, NoSync, 0, Universe{Number: 1}, at)
+	}
+	for i := 0; i < 3; i++ {
+		if err := send(now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := send(now); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("fourth packet in a second: %v, want ErrLimitExceeded", err)
+	}
+	if err := send(now.Add(time.Second)); err != nil {
+		t.Errorf("packet in the next second: %v", err)
+	}
+	frames := sentFrames(t, c.packets())
+	if len(frames) != 4 || frames[3].Sequence != 3 {
+		t.Errorf("%d packets sent, want 4 numbered without a gap for the refused one", len(frames))
+	}
+
+	s, _ = testSender(t, Config{Limits: Limits{MaxBytesPerSecond: 1000}})
+	if err := s.Send(0, Universe{Number: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(0, Universe{Number: 1}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("second full universe within 1000 bytes per second: %v, want ErrLimitExceeded", err)
+	}
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	s, c := testSender(t, Config{})
+	s.mu.Lock()
+	s.seq[1] = 255
+	s.mu.Unlock()
+	for _, u := range []uint16{1, 1, 2} {
+		if err := s.Send(0, Universe{Number: u}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Sync(7); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	frames := sentFrames(t, c.packets())
+	want := []struct {
+		universe uint16
+		seq      uint8
+	}{{1, 255}, {1, 0}, {2, 0}}
+	if len(frames) != len(want) {
+		t.Fatalf("%d data packets sent, want %d", len(frames), len(want))
+	}
+	for i, w := range want {
+		if frames[i].Universe.Number != w.universe || frames[i].Sequence != w.seq {
+			t.Errorf("packet %d is universe %d, sequence %d; want universe %d, sequence %d",
+				i, frames[i].Universe.Number, frames[i].Sequence, w.universe, w.seq)
+		}
+	}
+	var syncs []uint8
+	for _, p := range c.packets() {
+		if Classify(p.data) == PacketSync {
+			f, err := ParseSyncPacket(p.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			syncs = append(syncs, f.Sequence)
+		}
+	}
+	if len(syncs) != 2 || syncs[0] != 0 || syncs[1] != 1 {
+		t.Errorf("synchronization packets numbered %v, want [0 1]", syncs)
+	}
+}
+
+func TestCloseTerminates(t *testing.T) {
+	s, c := testSender(t, Config{})
+	l, err := s.Claim(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := Universe{Number: 1, Priorities: new([512]byte)}
+	u.Slots[0] = 10
+	if err := l.Send(0, u); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SendPriorities(0, u); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send(0, Universe{Number: 2}); err != nil {
+		t.Fatal(err)
+	}
+	sent := len(c.packets())
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	terminated := make(map[uint16]int)
+	for _, f := range sentFrames(t, c.packets()[sent:]) {
+		if !Options(f.Options).StreamTerminated() || f.StartCode != NullStartCode {
+			t.Errorf("universe %d closed with options %v, START code %#02x", f.Universe.Number, Options(f.Options), f.StartCode)
+		}
+		if f.Universe.Number == 1 && f.Universe.Slots[0] != 10 {
+			t.Errorf("termination carries level %d, want the last data sent", f.Universe.Slots[0])
+		}
+		terminated[f.Universe.Number]++
+	}
+	if terminated[1] != 3 || terminated[2] != 3 || len(terminated) != 2 {
+		t.Errorf("termination packets per universe %v, want 3 for each of 1 and 2", terminated)
+	}
+	if err := s.Send(0, Universe{Number: 2}); !errors.Is(err, ErrClosed) {
+		t.Errorf("send after Close: %v, want ErrClosed", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
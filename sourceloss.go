@@ -0,0 +1,179 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+// DataLossTimeout is E131_NETWORK_DATA_LOSS_TIMEOUT: a source that sends
+// nothing on a universe for this long is considered lost.
+const DataLossTimeout = 2500 * time.Millisecond
+
+// lossCheckInterval is how often a Receiver looks for timed-out sources.
+const lossCheckInterval = DataLossTimeout / 10
+
+// SourceLost describes a source that has stopped sending a universe.
+type SourceLost struct {
+	Universe   uint16
+	CID        uuid.UUID
+	SourceName string
+	// Terminated is true if the source announced the end of its stream
+	// with the stream terminated option, and false if it timed out.
+	Terminated bool
+}
+
+// sourceKey identifies one source's stream on one universe.
+type sourceKey struct {
+	universe uint16
+	cid      uuid.UUID
+}
+
+// sourceState is what a Receiver tracks about an online source.
+type sourceState struct {
+	name     string
+	lastSeen time.Time
+	// found is when the source was first received, and state is its
+	// StreamStateSampling or StreamStateActive state.
+	found time.Time
+	state StreamState
+}
+
+// OnSourceLost sets fn to be called whenever a source stops sending a joined
+// universe, either by terminating its stream or by sending nothing for
+// DataLossTimeout. fn may be called from the Receiver's goroutines
+// concurrently with the frame handler.
+//
+// Deprecated: Subscribe to EventSourceLost on an EventBus given to
+// SetEventBus, which carries every kind of notification.
+func (r *Receiver) OnSourceLost(fn func(SourceLost)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lost = fn
+}
+
+// Sources returns the CIDs of the sources currently sending universe.
+func (r *Receiver) Sources(universe uint16) []uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var cids []uuid.UUID
+	for k := range r.sources {
+		if k.universe == universe {
+			cids = append(cids, k.cid)
+		}
+	}
+	return cids
+}
+
+// track records that f arrived, moving its stream through the StreamState
+// machine and reporting the source lost if f terminates its stream. Known sources are updated in place, so steady traffic does not
+// allocate.
+func (r *Receiver) track(f DataFrame) {
+	k := sourceKey{f.Universe.Number, f.CID}
+	now := time.Now()
+	r.mu.Lock()
+	if f.Options&flpStreamTerminateFlag[0] == 0 {
+		if s := r.sources[k]; s != nil {
+			s.name, s.lastSeen = f.SourceName, now
+			t, changed := s.activate(k, now)
+			bus := r.bus
+			r.mu.Unlock()
+			if changed {
+				bus.publish(t)
+			}
+			return
+		}
+		r.sources[k] = &sourceState{name: f.SourceName, lastSeen: now, found: now, state: StreamStateSampling}
+		bus := r.bus
+		r.mu.Unlock()
+		bus.publish(SourceFound{Universe: k.universe, CID: k.cid, SourceName: f.SourceName})
+		bus.publish(StreamTransition{Universe: k.universe, CID: k.cid, SourceName: f.SourceName, From: StreamStateNone, To: StreamStateSampling})
+		return
+	}
+	s, known := r.sources[k]
+	delete(r.sources, k)
+	lost, bus := r.lost, r.bus
+	r.mu.Unlock()
+
+	if !known {
+		return
+	}
+	e := SourceLost{Universe: k.universe, CID: k.cid, SourceName: f.SourceName, Terminated: true}
+	if lost != nil {
+		lost(e)
+	}
+	bus.publish(e)
+	bus.publish(StreamTransition{Universe: k.universe, CID: k.cid, SourceName: f.SourceName, From: s.state, To: StreamStateTerminated})
+}
+
+// watchLoss reports sources that have timed out until the Receiver is
+// closed.
+func (r *Receiver) watchLoss() {
+	defer r.wg.Done()
+	tick := time.NewTicker(lossCheckInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-tick.C:
+			r.expire(now)
+		}
+	}
+}
+
+// expire drops sources not seen since DataLossTimeout before now, forgetting
+// the unexpected START codes they sent, activates those sampled for
+// SamplingPeriod, and reports synchronization addresses whose packets have
+// stopped for DataLossTimeout.
+func (r *Receiver) expire(now time.Time) {
+	var expired []SourceLost
+	var syncLost []SyncLost
+	var transitions []StreamTransition
+	r.mu.Lock()
+	for k, s := range r.sources {
+		if now.Sub(s.lastSeen) > DataLossTimeout {
+			delete(r.sources, k)
+			expired = append(expired, SourceLost{Universe: k.universe, CID: k.cid, SourceName: s.name})
+			transitions = append(transitions, StreamTransition{Universe: k.universe, CID: k.cid, SourceName: s.name, From: s.state, To: StreamStateLost})
+			continue
+		}
+		if t, changed := s.activate(k, now); changed {
+			transitions = append(transitions, t)
+		}
+	}
+	for k := range r.unexpected {
+		if _, ok := r.sources[k.source]; !ok {
+			delete(r.unexpected, k)
+		}
+	}
+	for addr, st := range r.syncs {
+		if !st.lastSync.IsZero() && !st.lost && now.Sub(st.lastSync) > DataLossTimeout {
+			st.lost = true
+			syncLost = append(syncLost, SyncLost{SyncAddr: addr})
+		}
+	}
+	lost, bus := r.lost, r.bus
+	subs := make([][]*Subscription, len(expired))
+	for i, e := range expired {
+		subs[i] = r.subs[e.Universe]
+	}
+	r.mu.Unlock()
+
+	for i, e := range expired {
+		if lost != nil {
+			lost(e)
+		}
+		for _, sub := range subs[i] {
+			if sub.lost != nil {
+				sub.lost(e)
+			}
+		}
+		bus.publish(e)
+	}
+	for _, e := range syncLost {
+		bus.publish(e)
+	}
+	for _, t := range transitions {
+		bus.publish(t)
+	}
+}
@@ -0,0 +1,46 @@
+package e131
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackAllocations(t *testing.T) {
+	r := NewReceiverConn(newMemConn(), func(DataFrame) {})
+	defer r.Close()
+	f := sourceFrames(1)[0]
+	r.track(f)
+	if n := testing.AllocsPerRun(100, func() { r.track(f) }); n != 0 {
+		t.Errorf("tracking a known source allocates %v times", n)
+	}
+}
+
+func TestSourceLost(t *testing.T) {
+	r := NewReceiverConn(newMemConn(), func(DataFrame) {})
+	defer r.Close()
+	var lost []SourceLost
+	r.OnSourceLost(func(l SourceLost) { lost = append(lost, l) })
+
+	frames := sourceFrames(2)
+	r.track(frames[0])
+	r.track(frames[1])
+	if n := len(r.Sources(1)); n != 2 {
+		t.Fatalf("%d sources, want 2", n)
+	}
+	frames[0].Options |= byte(StreamTerminated)
+	r.track(frames[0])
+	r.expire(time.Now().Add(DataLossTimeout + time.Millisecond))
+
+	if len(lost) != 2 {
+		t.Fatalf("%d sources lost, want 2", len(lost))
+	}
+	if lost[0].CID != frames[0].CID || !lost[0].Terminated {
+		t.Errorf("terminated source: %+v", lost[0])
+	}
+	if lost[1].CID != frames[1].CID || lost[1].Terminated {
+		t.Errorf("timed out source: %+v", lost[1])
+	}
+	if n := len(r.Sources(1)); n != 0 {
+		t.Errorf("%d sources left", n)
+	}
+}
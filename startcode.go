@@ -0,0 +1,102 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+// UnexpectedStartCode reports the first frame with a START code not expected
+// on a universe, see Receiver.ExpectStartCodes. It usually means a gateway
+// upstream is misconfigured, for example sending RDM on a data universe.
+type UnexpectedStartCode struct {
+	Universe   uint16
+	StartCode  byte
+	CID        uuid.UUID
+	SourceName string
+}
+
+// startCodeStats counts the frames of one universe by START code.
+type startCodeStats struct {
+	counts [256]uint64
+	// expected, if not nil, replaces the default expected START codes.
+	expected *[256]bool
+}
+
+// expects reports whether code is expected.
+func (st *startCodeStats) expects(code byte) bool {
+	if st.expected != nil {
+		return st.expected[code]
+	}
+	return code == NullStartCode || code == PriorityStartCode
+}
+
+// StartCodes returns how many frames of universe have been received with
+// each START code since it was first joined.
+func (r *Receiver) StartCodes(universe uint16) map[byte]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[byte]uint64)
+	if st := r.startCodes[universe]; st != nil {
+		for code, n := range st.counts {
+			if n > 0 {
+				counts[byte(code)] = n
+			}
+		}
+	}
+	return counts
+}
+
+// ExpectStartCodes sets the START codes expected on universe. The first
+// frame with any other START code, from each source, is published as an
+// UnexpectedStartCode on the EventBus. By default NullStartCode and
+// PriorityStartCode are expected; calling ExpectStartCodes with no codes
+// restores the default.
+func (r *Receiver) ExpectStartCodes(universe uint16, codes ...byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.startCodeStats(universe)
+	if len(codes) == 0 {
+		st.expected = nil
+		return
+	}
+	st.expected = new([256]bool)
+	for _, c := range codes {
+		st.expected[c] = true
+	}
+}
+
+// startCodeStats returns the statistics of universe, with r.mu held.
+func (r *Receiver) startCodeStats(universe uint16) *startCodeStats {
+	st := r.startCodes[universe]
+	if st == nil {
+		st = &startCodeStats{}
+		r.startCodes[universe] = st
+	}
+	return st
+}
+
+// countStartCode counts f and publishes an UnexpectedStartCode the first time
+// its source sends an unexpected START code on the universe.
+func (r *Receiver) countStartCode(f DataFrame) {
+	r.mu.Lock()
+	st := r.startCodeStats(f.Universe.Number)
+	st.counts[f.StartCode]++
+	if st.expects(f.StartCode) {
+		r.mu.Unlock()
+		return
+	}
+	k := unexpectedKey{sourceKey{f.Universe.Number, f.CID}, f.StartCode}
+	seen := r.unexpected[k]
+	r.unexpected[k] = true
+	bus := r.bus
+	r.mu.Unlock()
+	if !seen {
+		bus.publish(UnexpectedStartCode{Universe: f.Universe.Number, StartCode: f.StartCode, CID: f.CID, SourceName: f.SourceName})
+	}
+}
+
+// unexpectedKey identifies an unexpected START code from one source on one
+// universe.
+type unexpectedKey struct {
+	source    sourceKey
+	startCode byte
+}
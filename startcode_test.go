@@ -0,0 +1,55 @@
+package e131
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartCodes(t *testing.T) {
+	delivered := make(chan DataFrame, 8)
+	r, c := testReceiver(t, func(f DataFrame) { delivered <- f })
+	var unexpected []UnexpectedStartCode
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventUnexpectedStartCode}, func(e Event) {
+		unexpected = append(unexpected, e.(UnexpectedStartCode))
+	})
+	r.SetEventBus(bus)
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testSource()
+	packet := func(code byte) []byte {
+		b, err := appendStartCodePacket(nil, &cfg, NoSync, 0, 0, code, 1, make([]byte, 512))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	codes := []byte{NullStartCode, PriorityStartCode, 0xcc, NullStartCode, 0xcc}
+	for _, code := range codes {
+		c.deliver(packet(code), sourceAddr)
+	}
+	for range codes {
+		select {
+		case <-delivered:
+		case <-time.After(time.Second):
+			t.Fatal("frame not delivered")
+		}
+	}
+
+	got := r.StartCodes(1)
+	if len(got) != 3 || got[NullStartCode] != 2 || got[PriorityStartCode] != 1 || got[0xcc] != 2 {
+		t.Errorf("counted %v", got)
+	}
+	if len(unexpected) != 1 || unexpected[0].StartCode != 0xcc || unexpected[0].CID != cfg.CID {
+		t.Errorf("reported %+v, want 0xcc once", unexpected)
+	}
+
+	r.ExpectStartCodes(1, NullStartCode)
+	c.deliver(packet(PriorityStartCode), sourceAddr)
+	<-delivered
+	if len(unexpected) != 2 || unexpected[1].StartCode != PriorityStartCode {
+		t.Errorf("reported %+v, want 0xdd once no longer expected", unexpected)
+	}
+}
@@ -0,0 +1,82 @@
+package e131
+
+import (
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+// SamplingPeriod is how long a Receiver samples a newly found source before
+// taking it as active: E1.31's sampling period, during which a receiver
+// should learn of every source of a universe before acting on any.
+const SamplingPeriod = 1500 * time.Millisecond
+
+// StreamState is the state of one source's stream on one universe, as a
+// Receiver sees it. A stream starts in StreamStateSampling, becomes
+// StreamStateActive after SamplingPeriod, and ends in StreamStateTerminated
+// or StreamStateLost, after which the source is forgotten.
+type StreamState int
+
+// States of a stream.
+const (
+	// StreamStateNone is the state of a source that is not being received.
+	StreamStateNone StreamState = iota
+	// StreamStateSampling is a source found less than SamplingPeriod ago.
+	StreamStateSampling
+	// StreamStateActive is a source received for SamplingPeriod or longer.
+	StreamStateActive
+	// StreamStateTerminated is a source that has sent the stream
+	// terminated option.
+	StreamStateTerminated
+	// StreamStateLost is a source that has sent nothing for
+	// DataLossTimeout.
+	StreamStateLost
+)
+
+// String returns the name of the state, for example "Active".
+func (s StreamState) String() string {
+	switch s {
+	case StreamStateNone:
+		return "None"
+	case StreamStateSampling:
+		return "Sampling"
+	case StreamStateActive:
+		return "Active"
+	case StreamStateTerminated:
+		return "Terminated"
+	case StreamStateLost:
+		return "Lost"
+	}
+	return fmt.Sprintf("StreamState(%d)", int(s))
+}
+
+// StreamTransition reports that a source's stream on a universe changed
+// state. A found source goes from StreamStateNone to StreamStateSampling.
+type StreamTransition struct {
+	Universe   uint16
+	CID        uuid.UUID
+	SourceName string
+	From, To   StreamState
+}
+
+// StreamState returns the state of the stream of source cid on universe,
+// which is StreamStateNone unless the source is being received.
+func (r *Receiver) StreamState(universe uint16, cid uuid.UUID) StreamState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s := r.sources[sourceKey{universe, cid}]; s != nil {
+		return s.state
+	}
+	return StreamStateNone
+}
+
+// activate moves s, the stream of k, from sampling to active if
+// SamplingPeriod has passed by now, with r.mu held, and returns the
+// transition to publish, if any.
+func (s *sourceState) activate(k sourceKey, now time.Time) (StreamTransition, bool) {
+	if s.state != StreamStateSampling || now.Sub(s.found) < SamplingPeriod {
+		return StreamTransition{}, false
+	}
+	s.state = StreamStateActive
+	return StreamTransition{Universe: k.universe, CID: k.cid, SourceName: s.name, From: StreamStateSampling, To: StreamStateActive}, true
+}
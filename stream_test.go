@@ -0,0 +1,58 @@
+package e131
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamStates(t *testing.T) {
+	r := NewReceiverConn(newMemConn(), func(DataFrame) {})
+	defer r.Close()
+	var got []StreamTransition
+	bus := NewEventBus()
+	bus.Subscribe(EventFilter{Kinds: EventStreamTransition}, func(e Event) { got = append(got, e.(StreamTransition)) })
+	r.SetEventBus(bus)
+
+	frames := sourceFrames(2)
+	r.track(frames[0])
+	r.track(frames[1])
+	if s := r.StreamState(1, frames[0].CID); s != StreamStateSampling {
+		t.Errorf("new source is %v, want Sampling", s)
+	}
+	start := time.Now()
+	r.expire(start.Add(SamplingPeriod))
+	if s := r.StreamState(1, frames[0].CID); s != StreamStateActive {
+		t.Errorf("source sampled for SamplingPeriod is %v, want Active", s)
+	}
+	frames[0].Options |= byte(StreamTerminated)
+	r.track(frames[0])
+	r.expire(start.Add(DataLossTimeout + time.Second))
+	if s := r.StreamState(1, frames[1].CID); s != StreamStateNone {
+		t.Errorf("timed out source is %v, want None", s)
+	}
+
+	want := []struct {
+		source   int
+		from, to StreamState
+	}{
+		{0, StreamStateNone, StreamStateSampling},
+		{1, StreamStateNone, StreamStateSampling},
+		{0, StreamStateSampling, StreamStateActive},
+		{1, StreamStateSampling, StreamStateActive},
+		{0, StreamStateActive, StreamStateTerminated},
+		{1, StreamStateActive, StreamStateLost},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transitions, want %d: %v", len(got), len(want), got)
+	}
+	// Sources are activated in map order.
+	if got[2].CID != frames[0].CID {
+		got[2], got[3] = got[3], got[2]
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.CID != frames[w.source].CID || g.From != w.from || g.To != w.to {
+			t.Errorf("transition %d: source %v %v -> %v, want source %d %v -> %v", i, g.CID, g.From, g.To, w.source, w.from, w.to)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package e131
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscription is one consumer of a universe's frames on a Receiver.
+// Several subscriptions, and the Receiver's own handler, can share a
+// universe; its packets are received once and delivered to each.
+type Subscription struct {
+	r        *Receiver
+	universe uint16
+	deliver  func(DataFrame)
+	// lost, if set, is called when a source of the universe times out.
+	lost func(SourceLost)
+	// priority orders the subscriptions of a universe, highest first.
+	priority int
+	// output is the merged output stage of a SubscribeMerged subscription.
+	output *mergedOutput
+	// stop is closed by Cancel.
+	stop chan struct{}
+}
+
+// Subscribe delivers every frame of universe, from every source, to fn. It
+// joins the universe if needed. fn is called from the universe's receiving
+// goroutine, like the Receiver's handler.
+func (r *Receiver) Subscribe(universe uint16, fn func(DataFrame)) (*Subscription, error) {
+	return r.subscribe(&Subscription{r: r, universe: universe, deliver: fn})
+}
+
+// SubscribeMerged delivers the merged output of universe to fn each time a
+// frame or a source timeout changes it, merging with mode. Merging is private
+// to the subscription. Consumers slower than the stream should use LimitRate.
+func (r *Receiver) SubscribeMerged(universe uint16, mode MergeMode, fn func(Universe)) (*Subscription, error) {
+	m := NewMerger(mode)
+	out := &mergedOutput{fn: fn, wake: make(chan struct{}, 1)}
+	return r.subscribe(&Subscription{
+		r:        r,
+		universe: universe,
+		deliver: func(f DataFrame) {
+			out.send(m.Update(f))
+		},
+		lost: func(l SourceLost) {
+			out.send(m.Remove(l.Universe, l.CID))
+		},
+		output: out,
+	})
+}
+
+func (r *Receiver) subscribe(sub *Subscription) (*Subscription, error) {
+	if err := checkUniverse(sub.universe); err != nil {
+		return nil, err
+	}
+	sub.stop = make(chan struct{})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.joinLocked(sub.universe); err != nil {
+		return nil, err
+	}
+	subs := append([]*Subscription(nil), r.subs[sub.universe]...)
+	r.subs[sub.universe] = sortSubscriptions(append(subs, sub))
+	return sub, nil
+}
+
+// sortSubscriptions orders subs by priority, highest first, keeping the
+// order of subscription among equals.
+func sortSubscriptions(subs []*Subscription) []*Subscription {
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].priority > subs[j].priority
+	})
+	return subs
+}
+
+// SetPriority sets the order in which the subscriptions of a universe are
+// called for each frame: those with a higher priority first, so that a
+// real-time consumer is not delayed by slower ones. Subscriptions have
+// priority 0 by default, and equal priorities are called in the order they
+// subscribed. The Receiver's handler is called before any subscription.
+func (sub *Subscription) SetPriority(priority int) {
+	r := sub.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub.priority = priority
+	if subs, ok := r.subs[sub.universe]; ok {
+		r.subs[sub.universe] = sortSubscriptions(append([]*Subscription(nil), subs...))
+	}
+}
+
+// LimitRate caps a SubscribeMerged subscription to one call per interval.
+// Its function is then called from a goroutine of its own with the latest
+// merged output, and the outputs in between are skipped, so a slow consumer
+// such as a user interface never delays the receiving goroutine or the other
+// subscriptions. An interval of 0 lifts the cap but keeps the goroutine.
+// LimitRate fails on subscriptions made with Subscribe, whose frames cannot
+// be skipped.
+func (sub *Subscription) LimitRate(interval time.Duration) error {
+	if sub.output == nil {
+		return errorf(ErrInvalidArgument, "Cannot limit the rate of an unmerged subscription")
+	}
+	if interval < 0 {
+		return errorf(ErrInvalidArgument, "Invalid rate limit interval %v", interval)
+	}
+	o := sub.output
+	o.mu.Lock()
+	o.interval = interval
+	started := o.async
+	o.async = true
+	o.mu.Unlock()
+	if started {
+		return nil
+	}
+	r := sub.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.joined == nil {
+		return nil
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		o.run(sub.stop, r.done)
+	}()
+	return nil
+}
+
+// mergedOutput passes the output of a SubscribeMerged subscription to its
+// function, directly or, once rate limited, from its own goroutine.
+type mergedOutput struct {
+	fn   func(Universe)
+	wake chan struct{}
+
+	mu sync.Mutex
+	// async routes outputs through run.
+	async    bool
+	interval time.Duration
+	// latest is the output waiting for run, if pending.
+	latest  Universe
+	pending bool
+}
+
+// send delivers u, or leaves it for run.
+func (o *mergedOutput) send(u Universe) {
+	o.mu.Lock()
+	if !o.async {
+		o.mu.Unlock()
+		o.fn(u)
+		return
+	}
+	o.latest, o.pending = u, true
+	o.mu.Unlock()
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run calls fn with the latest pending output, at most once per interval,
+// until stop or done is closed.
+func (o *mergedOutput) run(stop, done <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-done:
+			return
+		case <-o.wake:
+		}
+		o.mu.Lock()
+		u, ok, interval := o.latest, o.pending, o.interval
+		o.pending = false
+		o.mu.Unlock()
+		if !ok {
+			continue
+		}
+		o.fn(u)
+		if interval == 0 {
+			continue
+		}
+		t := time.NewTimer(interval)
+		select {
+		case <-stop:
+			t.Stop()
+			return
+		case <-done:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Cancel stops the subscription. The universe stops being received once it
+// has no subscriptions left and was not joined with Join.
+func (sub *Subscription) Cancel() error {
+	r := sub.r
+	r.mu.Lock()
+	select {
+	case <-sub.stop:
+	default:
+		close(sub.stop)
+	}
+	var subs []*Subscription
+	for _, s := range r.subs[sub.universe] {
+		if s != sub {
+			subs = append(subs, s)
+		}
+	}
+	if len(subs) > 0 {
+		r.subs[sub.universe] = subs
+	} else {
+		delete(r.subs, sub.universe)
+	}
+	if len(subs) > 0 || r.handled[sub.universe] || r.joined == nil {
+		r.mu.Unlock()
+		return nil
+	}
+	return r.leaveLocked(sub.universe)
+}
+
+// deliver hands f to the handler, if its universe was joined with Join, and
+// to the universe's subscriptions.
+func (r *Receiver) deliver(f DataFrame) {
+	r.mu.Lock()
+	handled := r.handled[f.Universe.Number]
+	subs := r.subs[f.Universe.Number]
+	r.mu.Unlock()
+	if handled {
+		r.handler(f)
+	}
+	for _, sub := range subs {
+		sub.deliver(f)
+	}
+}
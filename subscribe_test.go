@@ -0,0 +1,185 @@
+package e131
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"net"
+	"testing"
+	"time"
+)
+
+// testReceiver returns a Receiver reading from a memConn, closed when t ends.
+func testReceiver(t *testing.T, handler func(DataFrame)) (*Receiver, *memConn) {
+	t.Helper()
+	c := newMemConn()
+	r := NewReceiverConn(c, handler)
+	t.Cleanup(func() { r.Close() })
+	return r, c
+}
+
+// levelPacket returns a data packet from cfg for universe with its first slot
+// set to level.
+func levelPacket(t testing.TB, cfg Config, universe uint16, seq, level uint8) []byte {
+	t.Helper()
+	u := Universe{Number: universe}
+	u.Slots[0] = level
+	b, err := cfg.DataPacket(NoSync, seq, 0, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// testSource is the configuration of a source sending at priority 100.
+func testSource() Config {
+	return Config{CID: uuid.NewV4(), SourceName: "source", Priority: 100}
+}
+
+var sourceAddr = &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: Port}
+
+func TestSubscribe(t *testing.T) {
+	handled := make(chan DataFrame, 4)
+	r, c := testReceiver(t, func(f DataFrame) { handled <- f })
+	if _, err := r.Subscribe(0, func(DataFrame) {}); err == nil {
+		t.Error("subscribed to universe 0")
+	}
+	frames := make(chan DataFrame, 4)
+	sub, err := r.Subscribe(1, func(f DataFrame) { frames <- f })
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := make(chan Universe, 4)
+	if _, err := r.SubscribeMerged(1, HTP, func(u Universe) { merged <- u }); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := testSource(), testSource()
+	c.deliver(levelPacket(t, a, 1, 0, 10), sourceAddr)
+	c.deliver(levelPacket(t, b, 1, 0, 20), sourceAddr)
+	for _, cfg := range []Config{a, b} {
+		if f := nextFrame(t, frames); f.CID != cfg.CID {
+			t.Errorf("got a frame from %s, want %s", f.CID, cfg.CID)
+		}
+	}
+	for _, want := range []uint8{10, 20} {
+		select {
+		case u := <-merged:
+			if u.Slots[0] != want {
+				t.Errorf("merged level %d, want %d", u.Slots[0], want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("merged output not delivered")
+		}
+	}
+	select {
+	case <-handled:
+		t.Error("handler called for a universe that was only subscribed to")
+	default:
+	}
+
+	if err := sub.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Cancel(); err != nil {
+		t.Errorf("second Cancel: %v", err)
+	}
+	c.deliver(levelPacket(t, a, 1, 1, 30), sourceAddr)
+	select {
+	case u := <-merged:
+		if u.Slots[0] != 30 {
+			t.Errorf("merged level %d, want 30", u.Slots[0])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("remaining subscription stopped by another's Cancel")
+	}
+	select {
+	case <-frames:
+		t.Error("cancelled subscription still called")
+	default:
+	}
+}
+
+func TestSubscriptionPriority(t *testing.T) {
+	r, c := testReceiver(t, func(DataFrame) {})
+	order := make(chan string, 3)
+	subscribe := func(name string) *Subscription {
+		sub, err := r.Subscribe(1, func(DataFrame) { order <- name })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub
+	}
+	subscribe("first")
+	subscribe("second")
+	subscribe("urgent").SetPriority(1)
+
+	c.deliver(levelPacket(t, testSource(), 1, 0, 0), sourceAddr)
+	for _, want := range []string{"urgent", "first", "second"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Errorf("called %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not called", want)
+		}
+	}
+}
+
+func TestLimitRate(t *testing.T) {
+	r, c := testReceiver(t, func(DataFrame) {})
+	sub, err := r.Subscribe(1, func(DataFrame) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.LimitRate(time.Second); err == nil {
+		t.Error("rate limit accepted on an unmerged subscription")
+	}
+
+	unblock := make(chan struct{})
+	slow := make(chan uint8, 16)
+	sub, err = r.SubscribeMerged(1, HTP, func(u Universe) {
+		<-unblock
+		slow <- u.Slots[0]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.LimitRate(-time.Second); err == nil {
+		t.Error("negative rate limit accepted")
+	}
+	if err := sub.LimitRate(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	fast := make(chan uint8, 16)
+	if _, err := r.SubscribeMerged(1, HTP, func(u Universe) { fast <- u.Slots[0] }); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testSource()
+	for i := uint8(1); i <= 10; i++ {
+		c.deliver(levelPacket(t, cfg, 1, i, i), sourceAddr)
+	}
+	for i := uint8(1); i <= 10; i++ {
+		select {
+		case got := <-fast:
+			if got != i {
+				t.Fatalf("fast subscriber got level %d, want %d", got, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("fast subscriber delayed by the slow one")
+		}
+	}
+	close(unblock)
+	var got []uint8
+	for len(got) == 0 || got[len(got)-1] != 10 {
+		select {
+		case l := <-slow:
+			got = append(got, l)
+		case <-time.After(time.Second):
+			t.Fatalf("slow subscriber got %v, want to end with 10", got)
+		}
+	}
+	if len(got) > 2 {
+		t.Errorf("slow subscriber called %d times, want the first and the latest output", len(got))
+	}
+}
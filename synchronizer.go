@@ -0,0 +1,36 @@
+package e131
+
+// Synchronizer sends a group of universes as one synchronized frame: each
+// universe's data is addressed to a shared synchronization universe, and a
+// synchronization packet follows, so that receivers update them all at once.
+type Synchronizer struct {
+	s        *Sender
+	syncAddr uint16
+}
+
+// NewSynchronizer returns a Synchronizer sending through s with the
+// synchronization universe syncAddr.
+func NewSynchronizer(s *Sender, syncAddr uint16) (*Synchronizer, error) {
+	if err := checkUniverse(syncAddr); err != nil {
+		return nil, err
+	}
+	return &Synchronizer{s: s, syncAddr: syncAddr}, nil
+}
+
+// SyncAddr returns the synchronization universe.
+func (y *Synchronizer) SyncAddr() uint16 {
+	return y.syncAddr
+}
+
+// Send transmits universes, addressed to the synchronization universe, and
+// then the synchronization packet. If any universe cannot be sent, Send
+// stops without sending the synchronization packet, so receivers keep
+// showing the previous frame.
+func (y *Synchronizer) Send(optionsFlags byte, universes ...Universe) error {
+	for _, u := range universes {
+		if err := y.s.SendSynced(y.syncAddr, optionsFlags, u); err != nil {
+			return err
+		}
+	}
+	return y.s.Sync(y.syncAddr)
+}
@@ -0,0 +1,91 @@
+package e131
+
+import (
+	"sort"
+	"time"
+)
+
+// syncState is what a synchronized Receiver tracks about one synchronization
+// address.
+type syncState struct {
+	// lastSync is when the last synchronization packet arrived, or zero if
+	// none has.
+	lastSync time.Time
+	// pending holds the latest frame per universe waiting for a
+	// synchronization packet.
+	pending map[uint16]DataFrame
+	// lost is set once the loss of synchronization has been reported.
+	lost bool
+}
+
+// SetSynchronized turns E1.31 synchronization on or off. When on, data
+// addressed to a synchronization universe is held until a synchronization
+// packet for that universe arrives, and then delivered; only the latest frame
+// per universe is kept. The synchronization universe must be joined as well.
+//
+// Data is delivered at once while no synchronization packet has yet been seen
+// for its address. If synchronization packets stop for DataLossTimeout, data
+// with the ForceSync option is delivered at once and other data stays held
+// until synchronization resumes. Synchronization is off by default.
+func (r *Receiver) SetSynchronized(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if on {
+		r.syncs = make(map[uint16]*syncState)
+	} else {
+		r.syncs = nil
+	}
+}
+
+// hold reports whether f must wait for a synchronization packet, keeping it
+// if so.
+func (r *Receiver) hold(f DataFrame, now time.Time) bool {
+	if f.SyncAddr == NoSync || Options(f.Options).StreamTerminated() {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.syncs == nil {
+		return false
+	}
+	st := r.syncs[f.SyncAddr]
+	if st == nil {
+		st = &syncState{pending: make(map[uint16]DataFrame)}
+		r.syncs[f.SyncAddr] = st
+	}
+	if st.lastSync.IsZero() {
+		return false
+	}
+	if now.Sub(st.lastSync) > DataLossTimeout && Options(f.Options).ForceSync() {
+		return false
+	}
+	st.pending[f.Universe.Number] = f
+	return true
+}
+
+// release records a synchronization packet for syncAddr and returns the
+// frames held for it, in universe order.
+func (r *Receiver) release(syncAddr uint16, now time.Time) []DataFrame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.syncs == nil {
+		return nil
+	}
+	st := r.syncs[syncAddr]
+	if st == nil {
+		st = &syncState{pending: make(map[uint16]DataFrame)}
+		r.syncs[syncAddr] = st
+	}
+	st.lastSync, st.lost = now, false
+	frames := make([]DataFrame, 0, len(st.pending))
+	for u, f := range st.pending {
+		if _, ok := r.joined[u]; ok {
+			frames = append(frames, f)
+		}
+		delete(st.pending, u)
+	}
+	sort.Slice(frames, func(i, j int) bool {
+		return frames[i].Universe.Number < frames[j].Universe.Number
+	})
+	return frames
+}
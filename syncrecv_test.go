@@ -0,0 +1,97 @@
+package e131
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncHold(t *testing.T) {
+	frames := make(chan DataFrame, 8)
+	r, c := testReceiver(t, func(f DataFrame) { frames <- f })
+	r.SetSynchronized(true)
+	for _, u := range []uint16{1, 2, 7} {
+		if err := r.Join(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, other := testSource(), testSource()
+	synced := func(universe uint16, seq, level uint8) []byte {
+		u := Universe{Number: universe}
+		u.Slots[0] = level
+		b, err := cfg.DataPacket(7, seq, 0, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	sync := func(seq uint8) []byte {
+		b, err := cfg.SyncPacket(7, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	c.deliver(synced(1, 0, 1), sourceAddr)
+	c.deliver(sync(0), sourceAddr)
+	c.deliver(synced(1, 1, 2), sourceAddr)
+	c.deliver(synced(2, 0, 3), sourceAddr)
+	c.deliver(synced(1, 2, 4), sourceAddr)
+	c.deliver(levelPacket(t, other, 2, 0, 5), sourceAddr)
+	c.deliver(sync(1), sourceAddr)
+
+	// Data is delivered at once until the first synchronization packet,
+	// then held for the next, keeping only the latest frame per universe.
+	for _, want := range []struct {
+		universe uint16
+		level    uint8
+	}{{1, 1}, {2, 5}, {1, 4}, {2, 3}} {
+		f := nextFrame(t, frames)
+		if f.Universe.Number != want.universe || f.Universe.Slots[0] != want.level {
+			t.Errorf("delivered universe %d level %d, want universe %d level %d",
+				f.Universe.Number, f.Universe.Slots[0], want.universe, want.level)
+		}
+	}
+	select {
+	case f := <-frames:
+		t.Errorf("superseded frame delivered: level %d", f.Universe.Slots[0])
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSyncLoss(t *testing.T) {
+	r, _ := testReceiver(t, func(DataFrame) {})
+	r.SetSynchronized(true)
+	if err := r.Join(1); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.release(7, now)
+
+	f := DataFrame{SyncAddr: 7, Universe: Universe{Number: 1}}
+	forced := f
+	forced.Options = byte(ForceSync)
+	terminated := f
+	terminated.Options = byte(StreamTerminated)
+	if !r.hold(forced, now.Add(DataLossTimeout/2)) {
+		t.Error("forced data not held while synchronized")
+	}
+	if r.hold(terminated, now) {
+		t.Error("stream termination held")
+	}
+	later := now.Add(DataLossTimeout + time.Millisecond)
+	if r.hold(forced, later) {
+		t.Error("forced data held after synchronization was lost")
+	}
+	if !r.hold(f, later) {
+		t.Error("unforced data released after synchronization was lost")
+	}
+	if got := r.release(7, later); len(got) != 1 || got[0].Options != 0 {
+		t.Errorf("released %v when synchronization resumed, want the unforced frame", got)
+	}
+
+	r.SetSynchronized(false)
+	if r.hold(f, later) {
+		t.Error("data held with synchronization off")
+	}
+}
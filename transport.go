@@ -0,0 +1,20 @@
+package e131
+
+import (
+	"net"
+)
+
+// PacketConn is the datagram transport used by Sender and Receiver.
+// *net.UDPConn satisfies it, and other implementations let the package run
+// over QUIC datagrams, serial links or in-memory test harnesses.
+//
+// Addresses passed to WriteTo are the *net.UDPAddr multicast groups derived
+// from universe numbers; a transport that is not IP-based may map or ignore
+// them. WriteTo must not retain p, which the Sender reuses for the next
+// packet. After Close, ReadFrom should return an error wrapping
+// net.ErrClosed or io.EOF.
+type PacketConn interface {
+	ReadFrom(p []byte) (n int, addr net.Addr, err error)
+	WriteTo(p []byte, addr net.Addr) (n int, err error)
+	Close() error
+}
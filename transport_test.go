@@ -0,0 +1,83 @@
+package e131
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// memConn is an in-memory PacketConn. It records every packet written to it
+// and reads the packets passed to deliver, or, if loop is set, the packets
+// written to it.
+type memConn struct {
+	loop bool
+
+	mu      sync.Mutex
+	written []memPacket
+	// fail maps destination addresses to the error WriteTo returns for
+	// them.
+	fail map[string]error
+
+	in     chan memPacket
+	closed chan struct{}
+	once   sync.Once
+}
+
+// memPacket is a packet passing through a memConn.
+type memPacket struct {
+	data []byte
+	addr net.Addr
+	at   time.Time
+}
+
+func newMemConn() *memConn {
+	return &memConn{in: make(chan memPacket, 256), closed: make(chan struct{})}
+}
+
+func (c *memConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case m := <-c.in:
+		return copy(p, m.data), m.addr, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *memConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.mu.Lock()
+	err := c.fail[addr.String()]
+	m := memPacket{append([]byte(nil), p...), addr, time.Now()}
+	if err == nil {
+		c.written = append(c.written, m)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if c.loop {
+		c.in <- m
+	}
+	return len(p), nil
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+// deliver queues data, from addr, to be read.
+func (c *memConn) deliver(data []byte, addr net.Addr) {
+	c.in <- memPacket{data: data, addr: addr}
+}
+
+// packets returns the packets written so far.
+func (c *memConn) packets() []memPacket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]memPacket(nil), c.written...)
+}
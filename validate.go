@@ -0,0 +1,104 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"sort"
+)
+
+// Validate lints c before a Sender is started with it. It returns the errors
+// NewSender would fail with, joined with the following conflicts, which
+// NewSender accepts but which are almost certainly mistakes:
+//
+//   - SyncAddr is also a universe with its own unicast or keep-alive
+//     settings, so that synchronization and data share a universe;
+//   - ForceSync is set without a SyncAddr, or advertising settings
+//     without Discovery, so they have no effect;
+//   - a unicast destination is listed twice for one universe, so that it
+//     receives every packet twice;
+//   - a DestinationKeepAlive entry matches no unicast destination;
+//   - a keep-alive interval is longer than DataLossTimeout, so that
+//     receivers time the source out between retransmissions;
+//   - Interface, which is only used for multicast, is down or cannot
+//     multicast.
+//
+// Every problem is reported as an ErrInvalidConfig.
+func (c Config) Validate() error {
+	var errs []error
+	if err := c.check(); err != nil {
+		errs = append(errs, err)
+	}
+	conflict := func(format string, args ...interface{}) {
+		errs = append(errs, errorf(ErrInvalidConfig, format, args...))
+	}
+
+	if c.Synchronized() {
+		if _, ok := c.Unicast[c.SyncAddr]; ok {
+			conflict("Sync address %d is also a unicast data universe", c.SyncAddr)
+		}
+		if _, ok := c.UniverseKeepAlive[c.SyncAddr]; ok {
+			conflict("Sync address %d also has a data keep-alive interval", c.SyncAddr)
+		}
+	} else if c.ForceSync {
+		conflict("ForceSync has no effect without a sync address")
+	}
+	if !c.Discovery && (c.AdvertiseChanges || c.AdvertiseInterval > 0) {
+		conflict("Advertising settings have no effect without Discovery")
+	}
+
+	universes := make([]uint16, 0, len(c.Unicast))
+	for u := range c.Unicast {
+		universes = append(universes, u)
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i] < universes[j] })
+	destinations := make(map[string]bool)
+	for _, universe := range universes {
+		seen := make(map[string]bool)
+		for _, a := range c.Unicast[universe] {
+			if a == nil {
+				continue
+			}
+			if seen[a.String()] {
+				conflict("Unicast destination %v is listed twice for universe %d", a, universe)
+			}
+			seen[a.String()], destinations[a.String()] = true, true
+		}
+	}
+	addrs := make([]string, 0, len(c.DestinationKeepAlive))
+	for a := range c.DestinationKeepAlive {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+	for _, a := range addrs {
+		if !destinations[a] {
+			conflict("Keep-alive destination %s is not a unicast destination", a)
+		}
+		if c.DestinationKeepAlive[a] > DataLossTimeout {
+			conflict("Keep-alive interval %v for %s exceeds the data loss timeout", c.DestinationKeepAlive[a], a)
+		}
+	}
+
+	if c.KeepAlive > DataLossTimeout {
+		conflict("Keep-alive interval %v exceeds the data loss timeout", c.KeepAlive)
+	}
+	universes = universes[:0]
+	for u := range c.UniverseKeepAlive {
+		universes = append(universes, u)
+	}
+	sort.Slice(universes, func(i, j int) bool { return universes[i] < universes[j] })
+	for _, universe := range universes {
+		if d := c.UniverseKeepAlive[universe]; d > DataLossTimeout {
+			conflict("Keep-alive interval %v for universe %d exceeds the data loss timeout", d, universe)
+		}
+	}
+
+	if ifi := c.Interface; ifi != nil {
+		if ifi.Flags&net.FlagUp == 0 {
+			conflict("Interface %s is down", ifi.Name)
+		}
+		if ifi.Flags&net.FlagMulticast == 0 {
+			conflict("Interface %s cannot send multicast", ifi.Name)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package e131
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	dest := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 5), Port: Port}
+	good := Config{SourceName: "test", Priority: 100, KeepAlive: DataLossTimeout / 3}
+	if err := good.Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		cfg  func(c *Config)
+		want string
+	}{
+		{"priority", func(c *Config) { c.Priority = 201 }, "Priority"},
+		{"sync unicast", func(c *Config) {
+			c.SyncAddr = 5
+			c.Unicast = map[uint16][]*net.UDPAddr{5: {dest}}
+		}, "Sync address 5"},
+		{"force sync", func(c *Config) { c.ForceSync = true }, "ForceSync"},
+		{"advertising", func(c *Config) { c.AdvertiseChanges = true }, "without Discovery"},
+		{"routed twice", func(c *Config) {
+			c.Unicast = map[uint16][]*net.UDPAddr{1: {dest, dest}}
+		}, "listed twice"},
+		{"unused destination", func(c *Config) {
+			c.DestinationKeepAlive = map[string]time.Duration{"10.0.0.9:5568": MinKeepAlive}
+		}, "not a unicast destination"},
+		{"slow keep-alive", func(c *Config) {
+			c.UniverseKeepAlive = map[uint16]time.Duration{3: 2 * DataLossTimeout}
+		}, "universe 3 exceeds"},
+		{"no multicast", func(c *Config) {
+			c.Interface = &net.Interface{Name: "tun0", Flags: net.FlagUp}
+		}, "cannot send multicast"},
+	} {
+		cfg := good
+		tc.cfg(&cfg)
+		err := cfg.Validate()
+		if !errors.Is(err, ErrInvalidConfig) && !errors.Is(err, ErrPriorityOutOfRange) {
+			t.Errorf("%s: got %v, want a config error", tc.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %q, want it to mention %q", tc.name, err, tc.want)
+		}
+	}
+}